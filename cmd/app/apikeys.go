@@ -0,0 +1,119 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/gorilla/mux"
+)
+
+//handleCreateAPIKey issues scoped API key for service account from json body
+func (s *Server) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleCreateAPIKey started")
+
+	adminId, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleCreateAPIKey middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	var item *types.APIKey
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleCreateAPIKey json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" || len(item.Scopes) == 0 {
+		loggers.ErrorLogger.Println("handleCreateAPIKey name or scopes are empty")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	item.CreatedBy = adminId
+
+	key, statusCode, err := s.usersSvc.CreateAPIKey(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleCreateAPIKey s.usersSvc.CreateAPIKey error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	// plaintext key is shown only in this response
+	w.Header().Set("Cache-Control", "no-store")
+	err = jsoner(w, key, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleCreateAPIKey jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleCreateAPIKey finished with any error!")
+}
+
+//handleAPIKeys returns all API keys
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleAPIKeys started")
+
+	keys, statusCode, err := s.usersSvc.APIKeys(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAPIKeys s.usersSvc.APIKeys error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, keys, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAPIKeys jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleAPIKeys finished with any error!")
+}
+
+//handleRevokeAPIKey revokes API key with id
+func (s *Server) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleRevokeAPIKey started")
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeAPIKey strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeAPIKey s.usersSvc.RevokeAPIKey error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeAPIKey jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleRevokeAPIKey finished with any error!")
+}
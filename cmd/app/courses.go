@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/gorilla/mux"
+)
+
+//handleTransferCourse makes user from json body owner of course with id
+func (s *Server) handleTransferCourse(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleTransferCourse started")
+
+	courseID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleTransferCourse strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	var item *types.CourseStaffInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleTransferCourse json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	item.CourseID = courseID
+
+	statusCode, err := s.usersSvc.TransferCourse(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleTransferCourse s.usersSvc.TransferCourse error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleTransferCourse jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleTransferCourse finished with any error!")
+}
+
+//handleAddCourseInstructor makes user from json body co-instructor of course with id
+func (s *Server) handleAddCourseInstructor(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleAddCourseInstructor started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAddCourseInstructor middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	courseID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAddCourseInstructor strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	var item *types.CourseStaffInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAddCourseInstructor json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	item.CourseID = courseID
+
+	statusCode, err := s.usersSvc.AddCourseInstructor(r.Context(), userID, item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAddCourseInstructor s.usersSvc.AddCourseInstructor error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAddCourseInstructor jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleAddCourseInstructor finished with any error!")
+}
+
+//handleRemoveCourseInstructor removes co-instructor with userId from course with id
+func (s *Server) handleRemoveCourseInstructor(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleRemoveCourseInstructor started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRemoveCourseInstructor middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	vars := mux.Vars(r)
+	courseID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRemoveCourseInstructor strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	instructorID, err := strconv.ParseInt(vars["userId"], 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRemoveCourseInstructor strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	item := &types.CourseStaffInfo{CourseID: courseID, UserID: instructorID}
+	statusCode, err := s.usersSvc.RemoveCourseInstructor(r.Context(), userID, item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRemoveCourseInstructor s.usersSvc.RemoveCourseInstructor error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRemoveCourseInstructor jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleRemoveCourseInstructor finished with any error!")
+}
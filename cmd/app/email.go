@@ -0,0 +1,77 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+)
+
+//handleVerifyEmail verifies email by token from query (link in email) or from json body
+func (s *Server) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleVerifyEmail started")
+
+	item := &types.VerifyEmailInfo{Token: r.URL.Query().Get("token")}
+	if r.Method == http.MethodPost {
+		err = json.NewDecoder(r.Body).Decode(&item)
+		if err != nil {
+			loggers.ErrorLogger.Println("handleVerifyEmail json.NewDecoder error:", err)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
+	statusCode, err := s.usersSvc.VerifyEmail(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleVerifyEmail s.usersSvc.VerifyEmail error:", err)
+		errorer(w, err, statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleVerifyEmail jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleVerifyEmail finished with any error!")
+}
+
+//handleResendEmailVerification sends new verification token to email of current user
+func (s *Server) handleResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleResendEmailVerification started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleResendEmailVerification middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	statusCode, err := s.usersSvc.ResendEmailVerification(r.Context(), userID)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleResendEmailVerification s.usersSvc.ResendEmailVerification error:", err)
+		errorer(w, err, statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleResendEmailVerification jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleResendEmailVerification finished with any error!")
+}
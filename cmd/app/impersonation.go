@@ -0,0 +1,103 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/gorilla/mux"
+)
+
+//handleImpersonate issues time-limited token of user with id for admin, reason is required for audit trail
+func (s *Server) handleImpersonate(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleImpersonate started")
+
+	adminId, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleImpersonate middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleImpersonate mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleImpersonate strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	var item *types.ImpersonateInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleImpersonate json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	item.Reason = strings.TrimSpace(item.Reason)
+	if item.Reason == "" {
+		loggers.ErrorLogger.Println("handleImpersonate reason is empty")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	item.AdminID = adminId
+	item.UserID = id
+	item.UserAgent = r.UserAgent()
+	item.IP = clientIP(r)
+
+	token, statusCode, err := s.usersSvc.Impersonate(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleImpersonate s.usersSvc.Impersonate error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	// plaintext token is shown only in this response
+	w.Header().Set("Cache-Control", "no-store")
+	err = jsoner(w, token, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleImpersonate jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleImpersonate finished with any error!")
+}
+
+//handleImpersonations returns audit trail of impersonations
+func (s *Server) handleImpersonations(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleImpersonations started")
+
+	impersonations, statusCode, err := s.usersSvc.Impersonations(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleImpersonations s.usersSvc.Impersonations error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, impersonations, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleImpersonations jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleImpersonations finished with any error!")
+}
@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/gorilla/mux"
+)
+
+// ErrNoPolicy is returned by Check when route has no declared policy
+var ErrNoPolicy = errors.New("route has no authorization policy")
+
+// PermissionFunc checks if user has permission, roles are roles carried by token or nil when they are not known,
+// status code is meaningful only with error
+type PermissionFunc func(ctx context.Context, userID int64, roles []string, permission string) (bool, int, error)
+
+type policyKind int
+
+const (
+	policyPublic policyKind = iota
+	policyAuthenticated
+	policyPermission
+	policyOwner
+)
+
+// Policy is authorization requirement of route
+type Policy struct {
+	kind       policyKind
+	permission string
+	param      string
+	// notImpersonated denies request made with impersonation token
+	notImpersonated bool
+	// scope allows request made with API key having it, API keys are denied when it is empty
+	scope string
+}
+
+// Public allows anonymous requests
+func Public() *Policy {
+	return &Policy{kind: policyPublic}
+}
+
+// Authenticated allows any authenticated user
+func Authenticated() *Policy {
+	return &Policy{kind: policyAuthenticated}
+}
+
+// Permission allows authenticated user with permission
+func Permission(permission string) *Policy {
+	return &Policy{kind: policyPermission, permission: permission}
+}
+
+// OwnerOrPermission allows authenticated user whose id is in path variable param,
+// other users need permission
+func OwnerOrPermission(param string, permission string) *Policy {
+	return &Policy{kind: policyOwner, param: param, permission: permission}
+}
+
+// NotImpersonated returns copy of policy which also denies requests made with impersonation token,
+// it is meant for sensitive actions such as password change
+func (p *Policy) NotImpersonated() *Policy {
+	policy := *p
+	policy.notImpersonated = true
+	return &policy
+}
+
+// Scope returns copy of policy which also allows requests made with API key having scope
+func (p *Policy) Scope(scope string) *Policy {
+	policy := *p
+	policy.scope = scope
+	return &policy
+}
+
+// allowsScopes checks if API key with scopes may use route
+func (p *Policy) allowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if p.scope != "" && scope == p.scope {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes policy for logs
+func (p *Policy) String() string {
+	var description string
+	switch p.kind {
+	case policyPublic:
+		description = "public"
+	case policyAuthenticated:
+		description = "authenticated"
+	case policyPermission:
+		description = "permission " + p.permission
+	case policyOwner:
+		description = fmt.Sprintf("owner of {%s} or permission %s", p.param, p.permission)
+	}
+	if p.notImpersonated {
+		description += ", not impersonated"
+	}
+	if p.scope != "" {
+		description += ", scope " + p.scope
+	}
+	return description
+}
+
+// Authorizer keeps policies of routes and enforces them
+type Authorizer struct {
+	permissionFunc PermissionFunc
+	policies       map[*mux.Route]*Policy
+}
+
+// NewAuthorizer creates authorizer checking permissions with permissionFunc
+func NewAuthorizer(permissionFunc PermissionFunc) *Authorizer {
+	return &Authorizer{permissionFunc: permissionFunc, policies: make(map[*mux.Route]*Policy)}
+}
+
+// Declare sets policy of route
+func (a *Authorizer) Declare(route *mux.Route, policy *Policy) *mux.Route {
+	a.policies[route] = policy
+	return route
+}
+
+// Check returns ErrNoPolicy listing every route of router with handler but without declared policy
+func (a *Authorizer) Check(router *mux.Router) error {
+	var missing []string
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		if _, ok := a.policies[route]; ok {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, _ := route.GetMethods()
+		missing = append(missing, strings.TrimSpace(strings.Join(methods, ",")+" "+path))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrNoPolicy, strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// Authorize is a middleware that enforces policy of matched route, it must run after Authenticate.
+// Route without policy is denied, request made with API key is denied unless policy declares scope of key
+func (a *Authorizer) Authorize(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy, ok := a.policies[mux.CurrentRoute(r)]
+		if !ok {
+			deny(w, r, "no policy", http.StatusForbidden)
+			return
+		}
+		if policy.kind == policyPublic {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		userID, err := Authentication(r.Context())
+		if err != nil {
+			Unauthorized(w, types.AuthNoCredentials)
+			return
+		}
+
+		if scopes, ok := Scopes(r.Context()); ok && !policy.allowsScopes(scopes) {
+			deny(w, r, policy.String(), http.StatusForbidden)
+			return
+		}
+
+		if _, ok := Impersonator(r.Context()); ok && policy.notImpersonated {
+			deny(w, r, policy.String(), http.StatusForbidden)
+			return
+		}
+
+		if policy.kind == policyOwner {
+			id, err := strconv.ParseInt(mux.Vars(r)[policy.param], 10, 64)
+			if err != nil {
+				deny(w, r, policy.String(), http.StatusBadRequest)
+				return
+			}
+			if id == userID {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if policy.kind == policyPermission || policy.kind == policyOwner {
+			roles, _ := Roles(r.Context())
+			allowed, statusCode, err := a.permissionFunc(r.Context(), userID, roles, policy.permission)
+			if err != nil {
+				deny(w, r, policy.String(), statusCode)
+				return
+			}
+			if !allowed {
+				deny(w, r, policy.String(), http.StatusForbidden)
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// deny logs denied request with description of policy and answers with code
+func deny(w http.ResponseWriter, r *http.Request, description string, code int) {
+	if loggers, err := GetLoggers(r.Context()); err == nil {
+		loggers.ErrorLogger.Printf("authorization denied %s %s: %s", r.Method, r.URL.Path, description)
+	}
+	http.Error(w, http.StatusText(code), code)
+}
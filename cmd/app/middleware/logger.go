@@ -25,21 +25,31 @@ type Loggers struct {
 	ErrorLogger *log.Logger
 }
 
-// LoggersFuncs is a middleware function that logs info to file
+// DefaultLogPath is path of log file used by LoggersFuncs
+const DefaultLogPath = "../log.log"
+
+// LoggersFuncs is a middleware function that logs info to file DefaultLogPath
 func LoggersFuncs(handle http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		f, err := os.OpenFile("../log.log", os.O_APPEND|os.O_RDWR|os.O_CREATE, 0666)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer f.Close()
-		f.Write([]byte("\r\n"))
-
-		ctx := context.WithValue(r.Context(), loggerContextKey, f)
-		r = r.WithContext(ctx)
-
-		handle.ServeHTTP(w, r)
-	})
+	return LoggersTo(DefaultLogPath)(handle)
+}
+
+// LoggersTo returns middleware function that logs info to file with path
+func LoggersTo(path string) func(http.Handler) http.Handler {
+	return func(handle http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0666)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			f.Write([]byte("\r\n"))
+
+			ctx := context.WithValue(r.Context(), loggerContextKey, f)
+			r = r.WithContext(ctx)
+
+			handle.ServeHTTP(w, r)
+		})
+	}
 }
 
 func GetLoggers(ctx context.Context) (*Loggers, error) {
@@ -4,12 +4,23 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
 )
 
 var ErrNoAuthentication = errors.New("no authentication")
 
 var authenticationContextKey = &contextKey{"authentication context"}
 
+var tokenContextKey = &contextKey{"token context"}
+
+var impersonatorContextKey = &contextKey{"impersonator context"}
+
+var scopesContextKey = &contextKey{"scopes context"}
+
+var rolesContextKey = &contextKey{"roles context"}
+
 type contextKey struct {
 	name string
 }
@@ -18,20 +29,45 @@ func (c *contextKey) String() string {
 	return c.name
 }
 
-type IDFunc func(ctx context.Context, token string) (int64, error)
+// AuthFunc authenticates token, error is returned only when authentication could not be performed
+type AuthFunc func(ctx context.Context, token string) (*types.AuthResult, error)
 
-func Authenticate(idFunc IDFunc) func(http.Handler) http.Handler {
+// Authenticate is a middleware that authenticates Bearer token of Authorization header.
+// Requests without token pass as anonymous, requests with bad token get 401
+func Authenticate(authFunc AuthFunc) func(http.Handler) http.Handler {
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := r.Header.Get("Authorization")
+			token, ok := bearerToken(r.Header.Get("Authorization"))
+			if !ok {
+				Unauthorized(w, types.AuthInvalid)
+				return
+			}
 			if token != "" {
-				id, err := idFunc(r.Context(), token)
+				result, err := authFunc(r.Context(), token)
 				if err != nil {
 					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 					return
 				}
+				if result.Status != types.AuthOK {
+					Unauthorized(w, result.Status)
+					return
+				}
 
-				ctx := context.WithValue(r.Context(), authenticationContextKey, id)
+				ctx := context.WithValue(r.Context(), authenticationContextKey, result.UserID)
+				ctx = context.WithValue(ctx, tokenContextKey, token)
+				if result.ImpersonatorID != 0 {
+					ctx = context.WithValue(ctx, impersonatorContextKey, result.ImpersonatorID)
+					if loggers, err := GetLoggers(r.Context()); err == nil {
+						loggers.InfoLogger.Printf("impersonated request: admin %d as user %d %s %s",
+							result.ImpersonatorID, result.UserID, r.Method, r.URL.Path)
+					}
+				}
+				if result.Scopes != nil {
+					ctx = context.WithValue(ctx, scopesContextKey, result.Scopes)
+				}
+				if result.Roles != nil {
+					ctx = context.WithValue(ctx, rolesContextKey, result.Roles)
+				}
 				r = r.WithContext(ctx)
 			}
 			handler.ServeHTTP(w, r)
@@ -39,9 +75,73 @@ func Authenticate(idFunc IDFunc) func(http.Handler) http.Handler {
 	}
 }
 
+// Unauthorized answers 401 with WWW-Authenticate header describing status
+func Unauthorized(w http.ResponseWriter, status types.AuthStatus) {
+	challenge := `Bearer realm="GoEDU"`
+	switch status {
+	case types.AuthInvalid:
+		challenge += `, error="invalid_token", error_description="invalid token"`
+	case types.AuthExpired:
+		challenge += `, error="invalid_token", error_description="token expired"`
+	case types.AuthRevoked:
+		challenge += `, error="invalid_token", error_description="token revoked"`
+	case types.AuthInactive:
+		challenge += `, error="invalid_token", error_description="user deactivated"`
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// bearerToken returns token from Authorization header value.
+// Value without scheme is taken as token for compatibility with old clients,
+// ok is false when scheme is not Bearer or token is missing
+func bearerToken(header string) (string, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", true
+	}
+
+	parts := strings.Fields(header)
+	switch {
+	case len(parts) == 1:
+		return parts[0], true
+	case len(parts) == 2 && strings.EqualFold(parts[0], "Bearer"):
+		return parts[1], true
+	}
+	return "", false
+}
+
 func Authentication(ctx context.Context) (int64, error) {
 	if value, ok := ctx.Value(authenticationContextKey).(int64); ok {
 		return value, nil
 	}
 	return 0, ErrNoAuthentication
 }
+
+// Impersonator returns id of admin when request is made with impersonation token,
+// Authentication returns id of impersonated user for such request
+func Impersonator(ctx context.Context) (int64, bool) {
+	value, ok := ctx.Value(impersonatorContextKey).(int64)
+	return value, ok
+}
+
+// Scopes returns scopes of API key when request is made with API key
+func Scopes(ctx context.Context) ([]string, bool) {
+	value, ok := ctx.Value(scopesContextKey).([]string)
+	return value, ok
+}
+
+// Roles returns roles carried by token when request is made with JWT access token
+func Roles(ctx context.Context) ([]string, bool) {
+	value, ok := ctx.Value(rolesContextKey).([]string)
+	return value, ok
+}
+
+// Token returns authenticated token of request
+func Token(ctx context.Context) (string, error) {
+	if value, ok := ctx.Value(tokenContextKey).(string); ok {
+		return value, nil
+	}
+	return "", ErrNoAuthentication
+}
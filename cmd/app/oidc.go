@@ -0,0 +1,76 @@
+package app
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+)
+
+//handleOIDCLogin redirects user to identity provider
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleOIDCLogin started")
+
+	url, statusCode, err := s.usersSvc.OIDCLogin(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleOIDCLogin s.usersSvc.OIDCLogin error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	http.Redirect(w, r, url, http.StatusFound)
+	loggers.InfoLogger.Println("handleOIDCLogin finished with any error!")
+}
+
+//handleOIDCCallback completes single sign-on and returns tokens
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleOIDCCallback started")
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		loggers.ErrorLogger.Println("handleOIDCCallback identity provider error:", providerErr)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	item := &types.OIDCCallbackInfo{
+		Code:      query.Get("code"),
+		State:     query.Get("state"),
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	}
+	if item.Code == "" || item.State == "" {
+		loggers.ErrorLogger.Println("handleOIDCCallback code or state not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	token, statusCode, err := s.usersSvc.OIDCCallback(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleOIDCCallback s.usersSvc.OIDCCallback error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	// plaintext tokens are shown only in this response
+	w.Header().Set("Cache-Control", "no-store")
+	err = jsoner(w, token, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleOIDCCallback jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleOIDCCallback finished with any error!")
+}
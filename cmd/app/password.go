@@ -0,0 +1,117 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+)
+
+//handleForgotPassword sends one-time password reset code to user
+func (s *Server) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleForgotPassword started")
+
+	var item *types.ForgotPasswordInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleForgotPassword json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.ForgotPassword(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleForgotPassword s.usersSvc.ForgotPassword error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleForgotPassword jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleForgotPassword finished with any error!")
+}
+
+//handleResetPassword sets new password by one-time code
+func (s *Server) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleResetPassword started")
+
+	var item *types.ResetPasswordInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleResetPassword json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.ResetPassword(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleResetPassword s.usersSvc.ResetPassword error:", err)
+		errorer(w, err, statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleResetPassword jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleResetPassword finished with any error!")
+}
+
+//handleChangePassword changes password of current user
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleChangePassword started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleChangePassword middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	var item *types.ChangePasswordInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleChangePassword json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	token, _ := middleware.Token(r.Context())
+	statusCode, err := s.usersSvc.ChangePassword(r.Context(), userID, token, item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleChangePassword s.usersSvc.ChangePassword error:", err)
+		errorer(w, err, statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleChangePassword jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleChangePassword finished with any error!")
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/gorilla/mux"
+)
+
+//handleMeProfile returns profile of user of token
+func (s *Server) handleMeProfile(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleMeProfile started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMeProfile middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	profile, statusCode, err := s.usersSvc.Profile(r.Context(), userID)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMeProfile s.usersSvc.Profile error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, profile, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMeProfile jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleMeProfile finished with any error!")
+}
+
+//handleUpdateMeProfile partially updates profile of user of token with fields from json body,
+//changing email requires current_password of user
+func (s *Server) handleUpdateMeProfile(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleUpdateMeProfile started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateMeProfile middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	patch, err := decodeProfilePatch(r)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateMeProfile decodeProfilePatch error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	profile, statusCode, err := s.usersSvc.UpdateProfile(r.Context(), userID, userID, patch)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateMeProfile s.usersSvc.UpdateProfile error:", err)
+		errorer(w, err, statusCode)
+		return
+	}
+
+	err = jsoner(w, profile, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateMeProfile jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleUpdateMeProfile finished with any error!")
+}
+
+//handleUserProfile returns profile of user with id
+func (s *Server) handleUserProfile(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleUserProfile started")
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserProfile strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	profile, statusCode, err := s.usersSvc.Profile(r.Context(), id)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserProfile s.usersSvc.Profile error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, profile, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserProfile jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleUserProfile finished with any error!")
+}
+
+//handleUpdateUserProfile partially updates profile of user with id with fields from json body,
+//changing email requires current_password of user of token
+func (s *Server) handleUpdateUserProfile(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleUpdateUserProfile started")
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateUserProfile strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	actorID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateUserProfile middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	patch, err := decodeProfilePatch(r)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateUserProfile decodeProfilePatch error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	profile, statusCode, err := s.usersSvc.UpdateProfile(r.Context(), actorID, id, patch)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateUserProfile s.usersSvc.UpdateProfile error:", err)
+		errorer(w, err, statusCode)
+		return
+	}
+
+	err = jsoner(w, profile, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUpdateUserProfile jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleUpdateUserProfile finished with any error!")
+}
+
+//decodeProfilePatch decodes profile patch from json body, unknown fields such as username are rejected
+func decodeProfilePatch(r *http.Request) (*types.ProfilePatch, error) {
+	patch := &types.ProfilePatch{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/gorilla/mux"
+)
+
+//handleRoles returns all roles with their permissions
+func (s *Server) handleRoles(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleRoles started")
+
+	roles, statusCode, err := s.usersSvc.Roles(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRoles s.usersSvc.Roles error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, roles, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRoles jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleRoles finished with any error!")
+}
+
+//handleUserRoles returns roles of user with id
+func (s *Server) handleUserRoles(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleUserRoles started")
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleUserRoles mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserRoles strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	roles, statusCode, err := s.usersSvc.UserRoles(r.Context(), id)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserRoles s.usersSvc.UserRoles error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, roles, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserRoles jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleUserRoles finished with any error!")
+}
+
+//handleAssignRole gives role from json body to user with id
+func (s *Server) handleAssignRole(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleAssignRole started")
+
+	actorID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAssignRole middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleAssignRole mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAssignRole strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	var item *types.RoleInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAssignRole json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	item.ActorID = actorID
+	item.UserID = id
+
+	statusCode, err := s.usersSvc.AssignRole(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAssignRole s.usersSvc.AssignRole error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleAssignRole jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleAssignRole finished with any error!")
+}
+
+//handleRevokeRole takes role from user with id
+func (s *Server) handleRevokeRole(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleRevokeRole started")
+
+	actorID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeRole middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeRole strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.RevokeRole(r.Context(), &types.RoleInfo{ActorID: actorID, UserID: id, Role: vars["role"]})
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeRole s.usersSvc.RevokeRole error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeRole jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleRevokeRole finished with any error!")
+}
+
+//handleRoleChanges returns audit trail of role changes
+func (s *Server) handleRoleChanges(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleRoleChanges started")
+
+	changes, statusCode, err := s.usersSvc.RoleChanges(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRoleChanges s.usersSvc.RoleChanges error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, changes, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRoleChanges jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleRoleChanges finished with any error!")
+}
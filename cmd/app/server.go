@@ -1,24 +1,84 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/lockout"
+	"github.com/SYSTEMTerror/GoEDU/pkg/policy"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
 	"github.com/SYSTEMTerror/GoEDU/pkg/users"
 	"github.com/gorilla/mux"
 )
 
+//usersService is users service used by handlers, it is implemented by *users.Service
+type usersService interface {
+	APIKeys(ctx context.Context) ([]*types.APIKey, int, error)
+	AddCourseInstructor(ctx context.Context, userID int64, item *types.CourseStaffInfo) (int, error)
+	AssignRole(ctx context.Context, item *types.RoleInfo) (int, error)
+	ChangePassword(ctx context.Context, userID int64, currentToken string, item *types.ChangePasswordInfo) (int, error)
+	ConfirmTwoFactor(ctx context.Context, userID int64, item *types.TwoFactorCodeInfo) (*types.RecoveryCodes, int, error)
+	CourseSubscribes(ctx context.Context, userID int64, courseID int64) ([]*types.User, int, error)
+	CreateAPIKey(ctx context.Context, item *types.APIKey) (*types.APIKey, int, error)
+	CreateCourse(ctx context.Context, userID int64, course *types.Course) (int, error)
+	DisableTwoFactor(ctx context.Context, userID int64, item *types.TwoFactorCodeInfo) (int, error)
+	EnrollTwoFactor(ctx context.Context, userID int64) (*types.TwoFactorEnrollment, int, error)
+	ForgotPassword(ctx context.Context, item *types.ForgotPasswordInfo) (int, error)
+	GetAllUsers(ctx context.Context) ([]*types.User, int, error)
+	GetUserByID(ctx context.Context, id int64) (*types.User, int, error)
+	HasRolesPermission(ctx context.Context, userID int64, roles []string, permission string) (bool, int, error)
+	IDByToken(ctx context.Context, token string) (*types.AuthResult, error)
+	Impersonate(ctx context.Context, item *types.ImpersonateInfo) (*types.Token, int, error)
+	Impersonations(ctx context.Context) ([]*types.Impersonation, int, error)
+	MakeAdmin(ctx context.Context, makeAdminInfo *types.MakeAdminInfo) (int, error)
+	OIDCCallback(ctx context.Context, item *types.OIDCCallbackInfo) (*types.Token, int, error)
+	OIDCLogin(ctx context.Context) (string, int, error)
+	Profile(ctx context.Context, userID int64) (*types.Profile, int, error)
+	PublicUsers(ctx context.Context, viewerID int64, users []*types.User) ([]*types.PublicUser, int, error)
+	RefreshToken(ctx context.Context, item *types.RefreshInfo) (*types.Token, int, error)
+	RegisterUser(ctx context.Context, item *types.RegInfo) (*types.User, int, error)
+	RemoveCourseInstructor(ctx context.Context, userID int64, item *types.CourseStaffInfo) (int, error)
+	ResendEmailVerification(ctx context.Context, userID int64) (int, error)
+	ResetPassword(ctx context.Context, item *types.ResetPasswordInfo) (int, error)
+	RevokeAPIKey(ctx context.Context, id int64) (int, error)
+	RevokeRole(ctx context.Context, item *types.RoleInfo) (int, error)
+	RevokeSession(ctx context.Context, userID int64, sessionID int64) (int, error)
+	RevokeToken(ctx context.Context, token string) (int, error)
+	RevokeUserTokens(ctx context.Context, userID int64) (int, error)
+	RoleChanges(ctx context.Context) ([]*types.RoleChange, int, error)
+	Roles(ctx context.Context) ([]*types.Role, int, error)
+	Sessions(ctx context.Context, userID int64, currentToken string) ([]*types.Session, int, error)
+	SetActive(ctx context.Context, id int64, active bool) (int, error)
+	Subscribe(ctx context.Context, subscribeInfo *types.SubscribeInfo) (int, error)
+	Token(ctx context.Context, item *types.TokenInfo) (*types.Token, int, error)
+	TransferCourse(ctx context.Context, item *types.CourseStaffInfo) (int, error)
+	UnlockUser(ctx context.Context, id int64) (int, error)
+	UpdateProfile(ctx context.Context, actorID int64, userID int64, patch *types.ProfilePatch) (*types.Profile, int, error)
+	UserCourses(ctx context.Context, userID int64) ([]*types.Course, int, error)
+	UserRoles(ctx context.Context, userID int64) ([]string, int, error)
+	UsernameAvailable(ctx context.Context, username string) (*types.UsernameAvailability, int, error)
+	VerifyEmail(ctx context.Context, item *types.VerifyEmailInfo) (int, error)
+	VerifyTwoFactor(ctx context.Context, item *types.TwoFactorLoginInfo) (*types.Token, int, error)
+}
+
 //Server is structure for server with mux from gorilla/mux
 type Server struct {
 	mux          *mux.Router
-	usersSvc     *users.Service
+	usersSvc     usersService
+	authorizer   *middleware.Authorizer
+	logPath      string
 }
 
 //NewServer creates new server with mux from gorilla/mux
 func NewServer(mux *mux.Router, usersSvc *users.Service) *Server {
-	return &Server{mux: mux, usersSvc: usersSvc}
+	return &Server{mux: mux, usersSvc: usersSvc, logPath: middleware.DefaultLogPath}
 }
 
 // ServeHTTP
@@ -26,26 +86,106 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mux.ServeHTTP(w, r)
 }
 
-//Init initializes server
-func (s *Server) Init() {
-	s.mux.Use(middleware.LoggersFuncs)
+//Init initializes server, every route declares authorization policy which is enforced by one middleware,
+//error is returned when any route has no policy
+func (s *Server) Init() error {
+	s.mux.Use(middleware.LoggersTo(s.logPath))
 
 	usersAuthenticateMd := middleware.Authenticate(s.usersSvc.IDByToken)
+	s.authorizer = middleware.NewAuthorizer(s.usersSvc.HasRolesPermission)
 
 	mainSubrouter := s.mux.PathPrefix("/api/v1").Subrouter()
-	mainSubrouter.Use(usersAuthenticateMd)
+	mainSubrouter.Use(usersAuthenticateMd, s.authorizer.Authorize)
+
+	public := middleware.Public()
+	authenticated := middleware.Authenticated()
+	sensitive := middleware.Authenticated().NotImpersonated()
 
-	mainSubrouter.HandleFunc("/login", s.handleLoginUser).Methods("POST")
-	mainSubrouter.HandleFunc("/register", s.handleRegisterUser).Methods("POST")
-	mainSubrouter.HandleFunc("/admin", s.handleMakeAdmin).Methods("POST")
-	mainSubrouter.HandleFunc("/subscribe", s.handleSubscribe).Methods("POST")
-	mainSubrouter.HandleFunc("/user/{id}", s.handleGetUserByID).Methods("GET")
+	s.handle(mainSubrouter, "/login", s.handleLoginUser, public, "POST")
+	s.handle(mainSubrouter, "/oidc/login", s.handleOIDCLogin, public, "GET")
+	s.handle(mainSubrouter, "/oidc/callback", s.handleOIDCCallback, public, "GET")
+	s.handle(mainSubrouter, "/login/2fa", s.handleLoginTwoFactor, public, "POST")
+	s.handle(mainSubrouter, "/2fa/enroll", s.handleEnrollTwoFactor, sensitive, "POST")
+	s.handle(mainSubrouter, "/2fa/confirm", s.handleConfirmTwoFactor, sensitive, "POST")
+	s.handle(mainSubrouter, "/2fa/disable", s.handleDisableTwoFactor, sensitive, "POST")
+	s.handle(mainSubrouter, "/token/refresh", s.handleRefreshToken, public, "POST")
+	s.handle(mainSubrouter, "/logout", s.handleLogout, authenticated, "POST")
+	s.handle(mainSubrouter, "/logout/all", s.handleLogoutAll, sensitive, "POST")
+	s.handle(mainSubrouter, "/sessions", s.handleSessions, authenticated, "GET")
+	s.handle(mainSubrouter, "/sessions/{id}", s.handleRevokeSession, authenticated, "DELETE")
+	s.handle(mainSubrouter, "/password/forgot", s.handleForgotPassword, public, "POST")
+	s.handle(mainSubrouter, "/password/reset", s.handleResetPassword, public, "POST")
+	s.handle(mainSubrouter, "/password/change", s.handleChangePassword, sensitive, "POST")
+	s.handle(mainSubrouter, "/verify-email", s.handleVerifyEmail, public, "GET", "POST")
+	s.handle(mainSubrouter, "/verify-email/resend", s.handleResendEmailVerification, authenticated, "POST")
+	s.handle(mainSubrouter, "/register", s.handleRegisterUser, public, "POST")
+	s.handle(mainSubrouter, "/register/available", s.handleUsernameAvailable, public, "GET")
+	s.handle(mainSubrouter, "/admin", s.handleMakeAdmin,
+		middleware.Permission(users.PermissionRoleAssign).NotImpersonated(), "POST")
+	s.handle(mainSubrouter, "/admin/logout/{id}", s.handleRevokeUserSessions,
+		middleware.Permission(users.PermissionUserLogout), "POST")
+	s.handle(mainSubrouter, "/admin/unlock/{id}", s.handleUnlockUser,
+		middleware.Permission(users.PermissionUserUnlock), "POST")
+	s.handle(mainSubrouter, "/admin/deactivate/{id}", s.handleDeactivateUser,
+		middleware.Permission(users.PermissionUserDeactivate), "POST")
+	s.handle(mainSubrouter, "/admin/activate/{id}", s.handleActivateUser,
+		middleware.Permission(users.PermissionUserDeactivate), "POST")
+	s.handle(mainSubrouter, "/admin/impersonate/{id}", s.handleImpersonate,
+		middleware.Permission(users.PermissionUserImpersonate).NotImpersonated(), "POST")
+	s.handle(mainSubrouter, "/admin/impersonations", s.handleImpersonations,
+		middleware.Permission(users.PermissionAuditRead), "GET")
+	s.handle(mainSubrouter, "/admin/role-changes", s.handleRoleChanges,
+		middleware.Permission(users.PermissionAuditRead), "GET")
+	s.handle(mainSubrouter, "/admin/apikeys", s.handleCreateAPIKey,
+		middleware.Permission(users.PermissionAPIKeyManage).NotImpersonated(), "POST")
+	s.handle(mainSubrouter, "/admin/apikeys", s.handleAPIKeys,
+		middleware.Permission(users.PermissionAPIKeyManage), "GET")
+	s.handle(mainSubrouter, "/admin/apikeys/{id}", s.handleRevokeAPIKey,
+		middleware.Permission(users.PermissionAPIKeyManage).NotImpersonated(), "DELETE")
+	s.handle(mainSubrouter, "/roles", s.handleRoles, authenticated, "GET")
+	s.handle(mainSubrouter, "/user/{id}/roles", s.handleUserRoles,
+		middleware.OwnerOrPermission("id", users.PermissionUserRead), "GET")
+	s.handle(mainSubrouter, "/user/{id}/roles", s.handleAssignRole,
+		middleware.Permission(users.PermissionRoleAssign).NotImpersonated(), "POST")
+	s.handle(mainSubrouter, "/user/{id}/roles/{role}", s.handleRevokeRole,
+		middleware.Permission(users.PermissionRoleAssign).NotImpersonated(), "DELETE")
+	s.handle(mainSubrouter, "/subscribe", s.handleSubscribe,
+		middleware.Permission(users.PermissionCourseSubscribe).Scope(users.ScopeEnrollmentsWrite), "POST")
+	s.handle(mainSubrouter, "/me", s.handleMe, authenticated.Scope(users.ScopeUsersRead), "GET")
+	s.handle(mainSubrouter, "/me/courses", s.handleMeCourses, authenticated.Scope(users.ScopeCoursesRead), "GET")
+	s.handle(mainSubrouter, "/me/profile", s.handleMeProfile, authenticated.Scope(users.ScopeUsersRead), "GET")
+	s.handle(mainSubrouter, "/me/profile", s.handleUpdateMeProfile, sensitive, "PATCH")
+	s.handle(mainSubrouter, "/user/{id}/profile", s.handleUserProfile,
+		middleware.OwnerOrPermission("id", users.PermissionUserRead).Scope(users.ScopeUsersRead), "GET")
+	s.handle(mainSubrouter, "/user/{id}/profile", s.handleUpdateUserProfile,
+		middleware.Permission(users.PermissionUserEdit).NotImpersonated(), "PATCH")
+	s.handle(mainSubrouter, "/user/{id}", s.handleGetUserByID,
+		middleware.Permission(users.PermissionUserRead).Scope(users.ScopeUsersRead), "GET")
+	s.handle(mainSubrouter, "/user/{id}/sessions", s.handleUserSessions,
+		middleware.Permission(users.PermissionUserRead), "GET")
 
 	courseSubrouter := mainSubrouter.PathPrefix("/course").Subrouter()
-	courseSubrouter.HandleFunc("/create", s.handleCreateCourse).Methods("POST")
-	courseSubrouter.HandleFunc("/user/all", s.handleGetAllUsers).Methods("GET")
-	courseSubrouter.HandleFunc("/user/{id}", s.handleUserCourses).Methods("GET")
-	courseSubrouter.HandleFunc("/subscribers/{id}", s.handleCourseSubscribes).Methods("GET")
+	s.handle(courseSubrouter, "/create", s.handleCreateCourse,
+		middleware.Permission(users.PermissionCourseCreate).Scope(users.ScopeCoursesWrite), "POST")
+	s.handle(courseSubrouter, "/user/all", s.handleGetAllUsers,
+		middleware.Permission(users.PermissionUserRead).Scope(users.ScopeUsersRead), "GET")
+	s.handle(courseSubrouter, "/user/{id}", s.handleUserCourses,
+		middleware.OwnerOrPermission("id", users.PermissionEnrollmentRead).Scope(users.ScopeCoursesRead), "GET")
+	s.handle(courseSubrouter, "/subscribers/{id}", s.handleCourseSubscribes,
+		middleware.Permission(users.PermissionCourseSubscribers).Scope(users.ScopeEnrollmentsRead), "GET")
+	s.handle(courseSubrouter, "/{id}/owner", s.handleTransferCourse,
+		middleware.Permission(users.PermissionCourseManage).Scope(users.ScopeCoursesWrite), "POST")
+	s.handle(courseSubrouter, "/{id}/instructors", s.handleAddCourseInstructor,
+		middleware.Permission(users.PermissionCourseCreate).Scope(users.ScopeCoursesWrite), "POST")
+	s.handle(courseSubrouter, "/{id}/instructors/{userId}", s.handleRemoveCourseInstructor,
+		middleware.Permission(users.PermissionCourseCreate).Scope(users.ScopeCoursesWrite), "DELETE")
+
+	return s.authorizer.Check(s.mux)
+}
+
+//handle registers handler for path and methods on router with authorization policy
+func (s *Server) handle(router *mux.Router, path string, handler http.HandlerFunc, policy *middleware.Policy, methods ...string) {
+	s.authorizer.Declare(router.HandleFunc(path, handler).Methods(methods...), policy)
 }
 
 //function jsoner marshal interfaces to json and write to response writer
@@ -66,3 +206,32 @@ func jsoner(w http.ResponseWriter, v interface{}, code int) error {
 	}
 	return nil
 }
+
+//function errorer writes error response with code, policy violations and conflicts are written as json,
+//Retry-After header is set for locked login and throttled requests
+func errorer(w http.ResponseWriter, err error, code int) {
+	var policyErr *policy.Error
+	if errors.As(err, &policyErr) {
+		jsoner(w, policyErr, code)
+		return
+	}
+	var conflictErr *users.ConflictError
+	if errors.As(err, &conflictErr) {
+		jsoner(w, conflictErr, code)
+		return
+	}
+	var retryAfter time.Duration
+	var lockedErr *lockout.LockedError
+	var throttleErr *users.ThrottleError
+	switch {
+	case errors.As(err, &lockedErr):
+		retryAfter = lockedErr.RetryAfter
+	case errors.As(err, &throttleErr):
+		retryAfter = throttleErr.RetryAfter
+	}
+	if retryAfter > 0 {
+		seconds := int64(math.Ceil(retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+	http.Error(w, http.StatusText(code), code)
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/gorilla/mux"
+)
+
+//handleSessions returns active sessions of current user
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleSessions started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleSessions middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	token, _ := middleware.Token(r.Context())
+	sessions, statusCode, err := s.usersSvc.Sessions(r.Context(), userID, token)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleSessions s.usersSvc.Sessions error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, sessions, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleSessions jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleSessions finished with any error!")
+}
+
+//handleRevokeSession revokes session with id of current user
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleRevokeSession started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeSession middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleRevokeSession mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	sessionID, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeSession strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.RevokeSession(r.Context(), userID, sessionID)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeSession s.usersSvc.RevokeSession error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeSession jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleRevokeSession finished with any error!")
+}
+
+//handleUserSessions returns active sessions of user with id
+func (s *Server) handleUserSessions(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleUserSessions started")
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleUserSessions mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserSessions strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	sessions, statusCode, err := s.usersSvc.Sessions(r.Context(), id, "")
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserSessions s.usersSvc.Sessions error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, sessions, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUserSessions jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleUserSessions finished with any error!")
+}
+
+//function clientIP returns ip address of request's client
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+)
+
+//handleLoginTwoFactor completes login by challenge and second factor code
+func (s *Server) handleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleLoginTwoFactor started")
+
+	var item *types.TwoFactorLoginInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLoginTwoFactor json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	token, statusCode, err := s.usersSvc.VerifyTwoFactor(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLoginTwoFactor s.usersSvc.VerifyTwoFactor error:", err)
+		errorer(w, err, statusCode)
+		return
+	}
+
+	// plaintext tokens are shown only in this response
+	w.Header().Set("Cache-Control", "no-store")
+	err = jsoner(w, token, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLoginTwoFactor jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleLoginTwoFactor finished with any error!")
+}
+
+//handleEnrollTwoFactor creates TOTP secret of current user
+func (s *Server) handleEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleEnrollTwoFactor started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleEnrollTwoFactor middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	enrollment, statusCode, err := s.usersSvc.EnrollTwoFactor(r.Context(), userID)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleEnrollTwoFactor s.usersSvc.EnrollTwoFactor error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	err = jsoner(w, enrollment, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleEnrollTwoFactor jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleEnrollTwoFactor finished with any error!")
+}
+
+//handleConfirmTwoFactor enables two-factor authentication of current user and returns recovery codes
+func (s *Server) handleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleConfirmTwoFactor started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleConfirmTwoFactor middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	var item *types.TwoFactorCodeInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleConfirmTwoFactor json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	codes, statusCode, err := s.usersSvc.ConfirmTwoFactor(r.Context(), userID, item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleConfirmTwoFactor s.usersSvc.ConfirmTwoFactor error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	err = jsoner(w, codes, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleConfirmTwoFactor jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleConfirmTwoFactor finished with any error!")
+}
+
+//handleDisableTwoFactor disables two-factor authentication of current user
+func (s *Server) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleDisableTwoFactor started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleDisableTwoFactor middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	var item *types.TwoFactorCodeInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleDisableTwoFactor json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.DisableTwoFactor(r.Context(), userID, item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleDisableTwoFactor s.usersSvc.DisableTwoFactor error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleDisableTwoFactor jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleDisableTwoFactor finished with any error!")
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/SYSTEMTerror/GoEDU/cmd/app/middleware"
 	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/SYSTEMTerror/GoEDU/pkg/users"
 	"github.com/gorilla/mux"
 )
 
@@ -32,11 +33,11 @@ func (s *Server) handleRegisterUser(w http.ResponseWriter, r *http.Request) {
 	user, statusCode, err := s.usersSvc.RegisterUser(r.Context(), item)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleRegisterUser s.usersSvc.RegisterUser error:", err)
-		http.Error(w, http.StatusText(statusCode), statusCode)
+		errorer(w, err, statusCode)
 		return
 	}
 
-	err = jsoner(w, user, statusCode)
+	err = jsoner(w, users.PublicUser(user, true), statusCode)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleRegisterUser jsoner error:", err)
 		return
@@ -44,6 +45,31 @@ func (s *Server) handleRegisterUser(w http.ResponseWriter, r *http.Request) {
 	loggers.InfoLogger.Println("handleRegisterUser finished with any error!")
 }
 
+//handleUsernameAvailable checks if username from query can be registered
+func (s *Server) handleUsernameAvailable(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleUsernameAvailable started")
+
+	availability, statusCode, err := s.usersSvc.UsernameAvailable(r.Context(), r.URL.Query().Get("username"))
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUsernameAvailable s.usersSvc.UsernameAvailable error:", err)
+		errorer(w, err, statusCode)
+		return
+	}
+
+	err = jsoner(w, availability, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUsernameAvailable jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleUsernameAvailable finished with any error!")
+}
+
 //handleLoginUser
 func (s *Server) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 	loggers, err := middleware.GetLoggers(r.Context())
@@ -61,14 +87,18 @@ func (s *Server) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
+	item.UserAgent = r.UserAgent()
+	item.IP = clientIP(r)
 
 	token, statusCode, err := s.usersSvc.Token(r.Context(), item)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleLoginUser s.usersSvc.Token error:", err)
-		http.Error(w, http.StatusText(statusCode), statusCode)
+		errorer(w, err, statusCode)
 		return
 	}
 
+	// plaintext tokens are shown only in this response
+	w.Header().Set("Cache-Control", "no-store")
 	err = jsoner(w, token, statusCode)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleLoginUser jsoner error:", err)
@@ -77,6 +107,259 @@ func (s *Server) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 	loggers.InfoLogger.Println("handleLoginUser finished with any error!")
 }
 
+//handleRefreshToken rotates access and refresh tokens
+func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleRefreshToken started")
+
+	var item *types.RefreshInfo
+	err = json.NewDecoder(r.Body).Decode(&item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRefreshToken json.NewDecoder error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	item.UserAgent = r.UserAgent()
+	item.IP = clientIP(r)
+
+	token, statusCode, err := s.usersSvc.RefreshToken(r.Context(), item)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRefreshToken s.usersSvc.RefreshToken error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	// plaintext tokens are shown only in this response
+	w.Header().Set("Cache-Control", "no-store")
+	err = jsoner(w, token, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRefreshToken jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleRefreshToken finished with any error!")
+}
+
+//handleLogout revokes the token of current request
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleLogout started")
+
+	token, err := middleware.Token(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLogout middleware.Token error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	statusCode, err := s.usersSvc.RevokeToken(r.Context(), token)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLogout s.usersSvc.RevokeToken error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLogout jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleLogout finished with any error!")
+}
+
+//handleLogoutAll revokes all tokens of current user
+func (s *Server) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleLogoutAll started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLogoutAll middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	statusCode, err := s.usersSvc.RevokeUserTokens(r.Context(), userID)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLogoutAll s.usersSvc.RevokeUserTokens error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleLogoutAll jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleLogoutAll finished with any error!")
+}
+
+//handleRevokeUserSessions revokes all tokens of user with id
+func (s *Server) handleRevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleRevokeUserSessions started")
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleRevokeUserSessions mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeUserSessions strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.RevokeUserTokens(r.Context(), id)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeUserSessions s.usersSvc.RevokeUserTokens error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleRevokeUserSessions jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleRevokeUserSessions finished with any error!")
+}
+
+//handleUnlockUser resets failed login attempts of user with id
+func (s *Server) handleUnlockUser(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleUnlockUser started")
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleUnlockUser mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUnlockUser strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.UnlockUser(r.Context(), id)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUnlockUser s.usersSvc.UnlockUser error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleUnlockUser jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleUnlockUser finished with any error!")
+}
+
+//handleDeactivateUser deactivates user with id and revokes all sessions of user
+func (s *Server) handleDeactivateUser(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleDeactivateUser started")
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleDeactivateUser mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleDeactivateUser strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.SetActive(r.Context(), id, false)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleDeactivateUser s.usersSvc.SetActive error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleDeactivateUser jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleDeactivateUser finished with any error!")
+}
+
+//handleActivateUser reactivates user with id
+func (s *Server) handleActivateUser(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleActivateUser started")
+
+	idParam, ok := mux.Vars(r)["id"]
+	if !ok {
+		loggers.ErrorLogger.Println("handleActivateUser mux.Vars(r) ID not found")
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleActivateUser strconv.ParseInt error:", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err := s.usersSvc.SetActive(r.Context(), id, true)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleActivateUser s.usersSvc.SetActive error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, nil, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleActivateUser jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleActivateUser finished with any error!")
+}
+
 //handleSubscribe subscribes a user to a course
 func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 	loggers, err := middleware.GetLoggers(r.Context())
@@ -89,7 +372,7 @@ func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 	userId, err := middleware.Authentication(r.Context())
 	if err != nil {
 		loggers.ErrorLogger.Println("handleSubscribe middleware.Authentication error:", err)
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
 		return
 	}
 
@@ -127,23 +410,10 @@ func (s *Server) handleMakeAdmin(w http.ResponseWriter, r *http.Request) {
 	}
 	loggers.InfoLogger.Println("handleMakeAdmin started")
 
-	adminId, err := middleware.Authentication(r.Context())
+	actorID, err := middleware.Authentication(r.Context())
 	if err != nil {
 		loggers.ErrorLogger.Println("handleMakeAdmin middleware.Authentication error:", err)
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-		return
-	}
-
-	isAdmin, statusCode, err := s.usersSvc.IsAdmin(r.Context(), adminId)
-	if err != nil {
-		loggers.ErrorLogger.Println("handleMakeAdmin s.usersSvc.IsAdmin error:", err)
-		http.Error(w, http.StatusText(statusCode), statusCode)
-		return
-	}
-	
-	if !isAdmin {
-		loggers.ErrorLogger.Println("handleMakeAdmin s.usersSvc.IsAdmin isAdmin:", isAdmin)
-		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
 		return
 	}
 
@@ -154,8 +424,9 @@ func (s *Server) handleMakeAdmin(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
+	makeAdminInfo.ActorID = actorID
 
-	statusCode, err = s.usersSvc.MakeAdmin(r.Context(), makeAdminInfo)
+	statusCode, err := s.usersSvc.MakeAdmin(r.Context(), makeAdminInfo)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleMakeAdmin s.usersSvc.MakeAdmin error:", err)
 		http.Error(w, http.StatusText(statusCode), statusCode)
@@ -180,23 +451,10 @@ func (s *Server) handleCreateCourse(w http.ResponseWriter, r *http.Request) {
 	}
 	loggers.InfoLogger.Println("handleCreateCourse started")
 
-	adminId, err := middleware.Authentication(r.Context())
+	userID, err := middleware.Authentication(r.Context())
 	if err != nil {
 		loggers.ErrorLogger.Println("handleCreateCourse middleware.Authentication error:", err)
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-		return
-	}
-
-	isAdmin, statusCode, err := s.usersSvc.IsAdmin(r.Context(), adminId)
-	if err != nil {
-		loggers.ErrorLogger.Println("handleCreateCourse s.usersSvc.IsAdmin error:", err)
-		http.Error(w, http.StatusText(statusCode), statusCode)
-		return
-	}
-
-	if !isAdmin {
-		loggers.ErrorLogger.Println("handleCreateCourse s.usersSvc.IsAdmin isAdmin:", isAdmin)
-		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
 		return
 	}
 
@@ -208,7 +466,7 @@ func (s *Server) handleCreateCourse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	statusCode, err = s.usersSvc.CreateCourse(r.Context(), course)
+	statusCode, err := s.usersSvc.CreateCourse(r.Context(), userID, course)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleCreateCourse s.usersSvc.CreateCourse error:", err)
 		http.Error(w, http.StatusText(statusCode), statusCode)
@@ -233,26 +491,6 @@ func (s *Server) handleGetUserByID(w http.ResponseWriter, r *http.Request) {
 	}
 	loggers.InfoLogger.Println("handleGetUserByID started")
 
-	adminId, err := middleware.Authentication(r.Context())
-	if err != nil {
-		loggers.ErrorLogger.Println("handleMakeAdmin middleware.Authentication error:", err)
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-		return
-	}
-
-	isAdmin, statusCode, err := s.usersSvc.IsAdmin(r.Context(), adminId)
-	if err != nil {
-		loggers.ErrorLogger.Println("handleMakeAdmin s.usersSvc.IsAdmin error:", err)
-		http.Error(w, http.StatusText(statusCode), statusCode)
-		return
-	}
-
-	if !isAdmin {
-		loggers.ErrorLogger.Println("handleMakeAdmin s.usersSvc.IsAdmin isAdmin:", isAdmin)
-		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
-		return
-	}
-
 	idParam, ok := mux.Vars(r)["id"]
 	if !ok {
 		loggers.ErrorLogger.Println("handleGetUserByID mux.Vars(r) ID not found")
@@ -272,7 +510,8 @@ func (s *Server) handleGetUserByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(statusCode), statusCode)
 		return
 	}
-	err = jsoner(w, user, statusCode)
+	// policy of route requires user:read permission which shows account fields
+	err = jsoner(w, users.PublicUser(user, true), statusCode)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleGetUserByID jsoner error:", err)
 		return
@@ -290,32 +529,28 @@ func (s *Server) handleGetAllUsers(w http.ResponseWriter, r *http.Request) {
 	}
 	loggers.InfoLogger.Println("handleGetAllUsers started")
 
-	adminId, err := middleware.Authentication(r.Context())
+	userID, err := middleware.Authentication(r.Context())
 	if err != nil {
-		loggers.ErrorLogger.Println("handleMakeAdmin middleware.Authentication error:", err)
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		loggers.ErrorLogger.Println("handleGetAllUsers middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
 		return
 	}
-	isAdmin, statusCode, err := s.usersSvc.IsAdmin(r.Context(), adminId)
+
+	usersArr, statusCode, err := s.usersSvc.GetAllUsers(r.Context())
 	if err != nil {
-		loggers.ErrorLogger.Println("handleMakeAdmin s.usersSvc.IsAdmin error:", err)
+		loggers.ErrorLogger.Println("handleGetAllUsers s.usersSvc.GetAllUsers error:", err)
 		http.Error(w, http.StatusText(statusCode), statusCode)
 		return
 	}
-	if !isAdmin {
-		loggers.ErrorLogger.Println("handleMakeAdmin s.usersSvc.IsAdmin isAdmin:", isAdmin)
-		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
-		return
-	}
 
-	usersArr, statusCode, err := s.usersSvc.GetAllUsers(r.Context())
+	views, statusCode, err := s.usersSvc.PublicUsers(r.Context(), userID, usersArr)
 	if err != nil {
-		loggers.ErrorLogger.Println("handleGetAllUsers s.usersSvc.GetAllUsers error:", err)
+		loggers.ErrorLogger.Println("handleGetAllUsers s.usersSvc.PublicUsers error:", err)
 		http.Error(w, http.StatusText(statusCode), statusCode)
 		return
 	}
 
-	err = jsoner(w, usersArr, statusCode)
+	err = jsoner(w, views, statusCode)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleGetAllUsers jsoner error:", err)
 		return
@@ -332,23 +567,10 @@ func (s *Server) handleCourseSubscribes(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	adminId, err := middleware.Authentication(r.Context())
+	userID, err := middleware.Authentication(r.Context())
 	if err != nil {
 		loggers.ErrorLogger.Println("handleCourseSubscribes middleware.Authentication error:", err)
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-		return
-	}
-
-	isAdmin, statusCode, err := s.usersSvc.IsAdmin(r.Context(), adminId)
-	if err != nil {
-		loggers.ErrorLogger.Println("handleCourseSubscribes s.usersSvc.IsAdmin error:", err)
-		http.Error(w, http.StatusText(statusCode), statusCode)
-		return
-	}
-
-	if !isAdmin {
-		loggers.ErrorLogger.Println("handleCourseSubscribes s.usersSvc.IsAdmin isAdmin:", isAdmin)
-		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
 		return
 	}
 
@@ -366,14 +588,21 @@ func (s *Server) handleCourseSubscribes(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	usersArr, statusCode, err := s.usersSvc.CourseSubscribes(r.Context(), courseID)
+	usersArr, statusCode, err := s.usersSvc.CourseSubscribes(r.Context(), userID, courseID)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleCourseSubscribes s.usersSvc.CourseSubscribes error:", err)
 		http.Error(w, http.StatusText(statusCode), statusCode)
 		return
 	}
 
-	err = jsoner(w, usersArr, statusCode)
+	views, statusCode, err := s.usersSvc.PublicUsers(r.Context(), userID, usersArr)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleCourseSubscribes s.usersSvc.PublicUsers error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, views, statusCode)
 	if err != nil {
 		loggers.ErrorLogger.Println("handleCourseSubscribes jsoner error:", err)
 		return
@@ -389,6 +618,7 @@ func (s *Server) handleUserCourses(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	loggers.InfoLogger.Println("handleUserCourses started")
 
 	userIDParam, ok := mux.Vars(r)["id"]
 	if !ok {
@@ -419,3 +649,67 @@ func (s *Server) handleUserCourses(w http.ResponseWriter, r *http.Request) {
 
 	loggers.InfoLogger.Println("handleUserCourses finished with any error!")
 }
+
+//handleMe returns user of token
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleMe started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMe middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	user, statusCode, err := s.usersSvc.GetUserByID(r.Context(), userID)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMe s.usersSvc.GetUserByID error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, users.PublicUser(user, true), statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMe jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleMe finished with any error!")
+}
+
+//handleMeCourses returns all courses of user of token
+func (s *Server) handleMeCourses(w http.ResponseWriter, r *http.Request) {
+	loggers, err := middleware.GetLoggers(r.Context())
+	if err != nil {
+		log.Println("LOGGERS DON'T WORK!!!")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	loggers.InfoLogger.Println("handleMeCourses started")
+
+	userID, err := middleware.Authentication(r.Context())
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMeCourses middleware.Authentication error:", err)
+		middleware.Unauthorized(w, types.AuthNoCredentials)
+		return
+	}
+
+	coursesArr, statusCode, err := s.usersSvc.UserCourses(r.Context(), userID)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMeCourses s.usersSvc.UserCourses error:", err)
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	err = jsoner(w, coursesArr, statusCode)
+	if err != nil {
+		loggers.ErrorLogger.Println("handleMeCourses jsoner error:", err)
+		return
+	}
+	loggers.InfoLogger.Println("handleMeCourses finished with any error!")
+}
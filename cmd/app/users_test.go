@@ -0,0 +1,133 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/hasher"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/SYSTEMTerror/GoEDU/pkg/users"
+	"github.com/gorilla/mux"
+)
+
+// stubUsersService answers handlers with user holding real password hash for viewer having permissions,
+// calling method it doesn't override panics
+type stubUsersService struct {
+	usersService
+	user        *types.User
+	viewerID    int64
+	permissions map[string]bool
+}
+
+func (s *stubUsersService) IDByToken(ctx context.Context, token string) (*types.AuthResult, error) {
+	return &types.AuthResult{UserID: s.viewerID, Status: types.AuthOK}, nil
+}
+
+func (s *stubUsersService) HasRolesPermission(ctx context.Context, userID int64, roles []string, permission string) (bool, int, error) {
+	return s.permissions[permission], http.StatusOK, nil
+}
+
+func (s *stubUsersService) RegisterUser(ctx context.Context, item *types.RegInfo) (*types.User, int, error) {
+	return s.user, http.StatusOK, nil
+}
+
+func (s *stubUsersService) GetUserByID(ctx context.Context, id int64) (*types.User, int, error) {
+	return s.user, http.StatusOK, nil
+}
+
+func (s *stubUsersService) GetAllUsers(ctx context.Context) ([]*types.User, int, error) {
+	return []*types.User{s.user}, http.StatusOK, nil
+}
+
+func (s *stubUsersService) CourseSubscribes(ctx context.Context, userID int64, courseID int64) ([]*types.User, int, error) {
+	return []*types.User{s.user}, http.StatusOK, nil
+}
+
+func (s *stubUsersService) PublicUsers(ctx context.Context, viewerID int64, usersArr []*types.User) ([]*types.PublicUser, int, error) {
+	return users.PublicUsersFor(viewerID, s.permissions[users.PermissionUserRead], usersArr), http.StatusOK, nil
+}
+
+// TestUserResponsesHaveNoPasswordHash calls every handler answering with users through router
+// and fails when response contains password hash or shows account fields to viewer without user:read
+func TestUserResponsesHaveNoPasswordHash(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "log.log")
+
+	admin := map[string]bool{
+		users.PermissionUserRead:          true,
+		users.PermissionCourseSubscribers: true,
+	}
+	teacher := map[string]bool{
+		users.PermissionCourseSubscribers: true,
+	}
+
+	requests := []struct {
+		viewerID    int64
+		permissions map[string]bool
+		method      string
+		path        string
+		body        string
+		withAccount bool
+	}{
+		{1, admin, "POST", "/api/v1/register", `{"username":"admin","password":"correct horse battery staple"}`, true},
+		{1, admin, "GET", "/api/v1/user/1", "", true},
+		{1, admin, "GET", "/api/v1/course/user/all", "", true},
+		{1, admin, "GET", "/api/v1/course/subscribers/1", "", true},
+		{2, teacher, "GET", "/api/v1/course/subscribers/1", "", false},
+	}
+
+	for _, algorithm := range []string{hasher.Bcrypt, hasher.Argon2id} {
+		config := hasher.DefaultConfig()
+		config.Algorithm = algorithm
+		config.BcryptCost = 4
+		config.Argon2Memory = 1024
+		h, err := hasher.New(config)
+		if err != nil {
+			t.Fatalf("hasher.New(%s) error: %v", algorithm, err)
+		}
+		hash, err := h.Hash("correct horse battery staple")
+		if err != nil {
+			t.Fatalf("Hash(%s) error: %v", algorithm, err)
+		}
+
+		user := &types.User{
+			ID:            1,
+			Username:      "admin",
+			Password:      hash,
+			Email:         "admin@example.com",
+			EmailVerified: true,
+			IsAdmin:       true,
+			Active:        true,
+			Created:       time.Now(),
+		}
+		for _, request := range requests {
+			stub := &stubUsersService{user: user, viewerID: request.viewerID, permissions: request.permissions}
+			server := &Server{mux: mux.NewRouter(), usersSvc: stub, logPath: logPath}
+			err = server.Init()
+			if err != nil {
+				t.Fatalf("Init error: %v", err)
+			}
+
+			r := httptest.NewRequest(request.method, request.path, strings.NewReader(request.body))
+			r.Header.Set("Authorization", "Bearer token")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, r)
+
+			body := recorder.Body.String()
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("%s %s %s answered %d: %s", algorithm, request.method, request.path, recorder.Code, body)
+			}
+			if strings.Contains(body, hash) || strings.Contains(body, `"password"`) {
+				t.Errorf("%s %s %s response contains password hash: %s", algorithm, request.method, request.path, body)
+			}
+			if strings.Contains(body, `"email"`) != request.withAccount {
+				t.Errorf("%s %s %s for viewer %d returned account fields %t: %s",
+					algorithm, request.method, request.path, request.viewerID, !request.withAccount, body)
+			}
+		}
+	}
+}
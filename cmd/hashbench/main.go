@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/hasher"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//hashbench measures password hashing on this machine and suggests cost fitting target login latency,
+//suggested values are meant for GOEDU_BCRYPT_COST or GOEDU_ARGON2_TIME and GOEDU_ARGON2_MEMORY
+func main() {
+	algorithm := flag.String("alg", hasher.Bcrypt, "hashing algorithm: bcrypt or argon2id")
+	target := flag.Duration("target", 250*time.Millisecond, "target duration of one hash")
+	memory := flag.Uint("memory", 64*1024, "argon2id memory in KiB")
+	flag.Parse()
+
+	var err error
+	switch *algorithm {
+	case hasher.Bcrypt:
+		err = benchBcrypt(*target)
+	case hasher.Argon2id:
+		err = benchArgon2(*target, uint32(*memory))
+	default:
+		err = hasher.ErrUnknownAlgorithm
+	}
+	if err != nil {
+		log.Print(err)
+		os.Exit(1)
+	}
+}
+
+//benchBcrypt increases cost while hash fits target, every next cost doubles duration
+func benchBcrypt(target time.Duration) error {
+	config := hasher.DefaultConfig()
+	suggested := 0
+	for cost := bcrypt.MinCost; cost <= bcrypt.MaxCost; cost++ {
+		config.BcryptCost = cost
+		duration, err := measure(config)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("cost=%d\t%v\n", cost, duration)
+		if duration > target {
+			break
+		}
+		suggested = cost
+	}
+
+	if suggested == 0 {
+		fmt.Printf("even minimal cost %d is slower than %v\n", bcrypt.MinCost, target)
+		return nil
+	}
+	fmt.Printf("suggested: GOEDU_HASH_ALG=%s GOEDU_BCRYPT_COST=%d\n", hasher.Bcrypt, suggested)
+	return nil
+}
+
+//benchArgon2 increases number of passes with fixed memory while hash fits target
+func benchArgon2(target time.Duration, memory uint32) error {
+	config := hasher.DefaultConfig()
+	config.Algorithm = hasher.Argon2id
+	config.Argon2Memory = memory
+	var suggested uint32
+	for passes := uint32(1); passes <= 32; passes++ {
+		config.Argon2Time = passes
+		duration, err := measure(config)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("t=%d m=%d\t%v\n", passes, memory, duration)
+		if duration > target {
+			break
+		}
+		suggested = passes
+	}
+
+	if suggested == 0 {
+		fmt.Printf("even one pass over %d KiB is slower than %v, try lower -memory\n", memory, target)
+		return nil
+	}
+	fmt.Printf("suggested: GOEDU_HASH_ALG=%s GOEDU_ARGON2_TIME=%d GOEDU_ARGON2_MEMORY=%d\n",
+		hasher.Argon2id, suggested, memory)
+	return nil
+}
+
+//measure returns best of three durations of hashing with config
+func measure(config *hasher.Config) (time.Duration, error) {
+	h, err := hasher.New(config)
+	if err != nil {
+		return 0, err
+	}
+
+	var best time.Duration
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		_, err = h.Hash("hashbench password")
+		if err != nil {
+			return 0, err
+		}
+		duration := time.Since(start)
+		if best == 0 || duration < best {
+			best = duration
+		}
+	}
+	return best, nil
+}
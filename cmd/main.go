@@ -2,19 +2,37 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/SYSTEMTerror/GoEDU/cmd/app"
+	"github.com/SYSTEMTerror/GoEDU/pkg/hasher"
+	"github.com/SYSTEMTerror/GoEDU/pkg/jwt"
+	"github.com/SYSTEMTerror/GoEDU/pkg/lockout"
+	"github.com/SYSTEMTerror/GoEDU/pkg/mailer"
+	"github.com/SYSTEMTerror/GoEDU/pkg/oidc"
+	"github.com/SYSTEMTerror/GoEDU/pkg/policy"
 	"github.com/SYSTEMTerror/GoEDU/pkg/users"
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/dig"
 )
 
+var (
+	//ErrUnknownAuthBackend is returned when GOEDU_AUTH_BACKEND is neither "db" nor "jwt"
+	ErrUnknownAuthBackend = errors.New("unknown auth backend")
+	//ErrUnknownLockoutStore is returned when GOEDU_LOCKOUT_STORE is neither "postgres" nor "memory"
+	ErrUnknownLockoutStore = errors.New("unknown lockout store")
+)
+
 func main() {
 	host := "0.0.0.0"
 	port := "9999"
@@ -31,9 +49,31 @@ func execute(host string, port string, dsn string) (err error) {
 		app.NewServer,
 		mux.NewRouter,
 		func() (*pgxpool.Pool, error) {
-			ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
 			return pgxpool.Connect(ctx, dsn)
 		},
+		newSigner,
+		newMailer,
+		func() (*policy.Policy, error) {
+			config := policy.DefaultConfig()
+			//GOEDU_PASSWORD_DENYLIST is path to list replacing embedded pkg/policy/password_denylist.txt
+			config.DenylistPath = os.Getenv("GOEDU_PASSWORD_DENYLIST")
+			return policy.New(config)
+		},
+		newHasher,
+		newLoginStore,
+		func(store lockout.Store) *lockout.Limiter {
+			return lockout.NewLimiter(store, lockout.DefaultUserConfig(), lockout.DefaultIPConfig())
+		},
+		func() *users.Config {
+			return &users.Config{
+				Issuer:               "GoEDU",
+				RequireAdmin2FA:      os.Getenv("GOEDU_REQUIRE_ADMIN_2FA") == "true",
+				RequireVerifiedEmail: os.Getenv("GOEDU_REQUIRE_VERIFIED_EMAIL") == "true",
+			}
+		},
+		newOIDCProvider,
 		users.NewService,
 		func(server *app.Server) *http.Server {
 			return &http.Server{
@@ -51,8 +91,8 @@ func execute(host string, port string, dsn string) (err error) {
 		}
 	}
 
-	err = container.Invoke(func(server *app.Server) {
-		server.Init()
+	err = container.Invoke(func(server *app.Server) error {
+		return server.Init()
 	})
 	if err != nil {
 		return err
@@ -62,3 +102,116 @@ func execute(host string, port string, dsn string) (err error) {
 		return server.ListenAndServe()
 	})
 }
+
+//newSigner creates JWT signer when GOEDU_AUTH_BACKEND is "jwt", nil signer means tokens are checked in database.
+//GOEDU_JWT_KEYS is comma separated list of kid:base64 secret (32 bytes seed for EdDSA),
+//GOEDU_JWT_KID is kid used for signing, older keys stay in list for verification only.
+func newSigner() (*jwt.Manager, error) {
+	switch os.Getenv("GOEDU_AUTH_BACKEND") {
+	case "", "db":
+		return nil, nil
+	case "jwt":
+	default:
+		return nil, ErrUnknownAuthBackend
+	}
+
+	algorithm := os.Getenv("GOEDU_JWT_ALG")
+	if algorithm == "" {
+		algorithm = jwt.HS256
+	}
+
+	var keys []*jwt.Key
+	for _, item := range strings.Split(os.Getenv("GOEDU_JWT_KEYS"), ",") {
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return nil, jwt.ErrInvalidKey
+		}
+		secret, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwt.NewKey(parts[0], algorithm, secret)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return jwt.NewManager(os.Getenv("GOEDU_JWT_KID"), keys...)
+}
+
+//newMailer creates mailer appending messages to file GOEDU_MAIL_LOG, goedu-mail.log in temporary directory by default
+func newMailer() mailer.Mailer {
+	path := os.Getenv("GOEDU_MAIL_LOG")
+	if path == "" {
+		path = filepath.Join(os.TempDir(), "goedu-mail.log")
+	}
+
+	return mailer.NewFileMailer(path)
+}
+
+//newHasher creates password hasher, GOEDU_HASH_ALG is "bcrypt" or "argon2id",
+//cost is set by GOEDU_BCRYPT_COST or GOEDU_ARGON2_TIME and GOEDU_ARGON2_MEMORY (KiB), see cmd/hashbench.
+//Hashes made with other algorithm or cost are upgraded at next login
+func newHasher() (*hasher.Hasher, error) {
+	config := hasher.DefaultConfig()
+	if algorithm := os.Getenv("GOEDU_HASH_ALG"); algorithm != "" {
+		config.Algorithm = algorithm
+	}
+	if value := os.Getenv("GOEDU_BCRYPT_COST"); value != "" {
+		cost, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		config.BcryptCost = cost
+	}
+	if value := os.Getenv("GOEDU_ARGON2_TIME"); value != "" {
+		time, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		config.Argon2Time = uint32(time)
+	}
+	if value := os.Getenv("GOEDU_ARGON2_MEMORY"); value != "" {
+		memory, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		config.Argon2Memory = uint32(memory)
+	}
+
+	return hasher.New(config)
+}
+
+//newLoginStore creates store of failed login attempts selected by GOEDU_LOCKOUT_STORE,
+//"memory" store is not shared between instances and is meant for single instance and development
+func newLoginStore(pool *pgxpool.Pool) (lockout.Store, error) {
+	switch os.Getenv("GOEDU_LOCKOUT_STORE") {
+	case "", "postgres":
+		return lockout.NewPostgresStore(pool), nil
+	case "memory":
+		return lockout.NewMemoryStore(), nil
+	}
+	return nil, ErrUnknownLockoutStore
+}
+
+//newOIDCProvider creates OpenID Connect provider when GOEDU_OIDC_ISSUER is set, nil provider disables single sign-on
+func newOIDCProvider() *oidc.Provider {
+	issuer := os.Getenv("GOEDU_OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+
+	redirectURL := os.Getenv("GOEDU_OIDC_REDIRECT_URL")
+	if redirectURL == "" {
+		redirectURL = "http://localhost:9999/api/v1/oidc/callback"
+	}
+
+	return oidc.NewProvider(&oidc.Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("GOEDU_OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("GOEDU_OIDC_CLIENT_SECRET"),
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"profile", "email"},
+	})
+}
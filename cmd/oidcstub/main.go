@@ -0,0 +1,193 @@
+// Command oidcstub is a local stub OpenID Connect identity provider for development and manual testing.
+// It approves every authorization request for the user given by login_hint query parameter
+// (STUB_SUBJECT by default) without asking for credentials.
+//
+//	GOEDU_OIDC_ISSUER=http://localhost:9998 GOEDU_OIDC_CLIENT_ID=goedu go run ./cmd
+//	go run ./cmd/oidcstub
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const keyID = "stub"
+
+type grant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	subject     string
+	expires     time.Time
+}
+
+type stub struct {
+	issuer string
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	grants map[string]*grant
+}
+
+func main() {
+	addr := getEnv("STUB_ADDR", "localhost:9998")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := &stub{issuer: "http://" + addr, key: key, grants: make(map[string]*grant)}
+	http.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	http.HandleFunc("/authorize", s.handleAuthorize)
+	http.HandleFunc("/token", s.handleToken)
+	http.HandleFunc("/jwks", s.handleJWKS)
+
+	log.Println("stub identity provider listens on", s.issuer)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
+func (s *stub) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *stub) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	subject := query.Get("login_hint")
+	if subject == "" {
+		subject = getEnv("STUB_SUBJECT", "stub-user")
+	}
+
+	s.mu.Lock()
+	s.grants[code] = &grant{
+		clientID:    query.Get("client_id"),
+		redirectURI: redirectURI.String(),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		subject:     subject,
+		expires:     time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+
+	values := redirectURI.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirectURI.RawQuery = values.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *stub) handleToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		http.Error(w, "invalid token request", http.StatusBadRequest)
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	g, ok := s.grants[code]
+	delete(s.grants, code)
+	s.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || time.Now().After(g.expires) || g.clientID != r.PostForm.Get("client_id") ||
+		g.redirectURI != r.PostForm.Get("redirect_uri") ||
+		g.challenge != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		http.Error(w, "invalid grant", http.StatusBadRequest)
+		return
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":                s.issuer,
+		"sub":                g.subject,
+		"aud":                g.clientID,
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              g.nonce,
+		"preferred_username": g.subject,
+		"email":              g.subject + "@example.com",
+		"email_verified":     true,
+	})
+	signingInput := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signingInput + "." + encode(signature),
+	})
+}
+
+func (s *stub) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   encode(s.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Println("writeJSON error:", err)
+	}
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func randomString() string {
+	buffer := make([]byte, 32)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return encode(buffer)
+}
+
+func getEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
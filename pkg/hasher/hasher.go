@@ -0,0 +1,197 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Bcrypt is bcrypt algorithm, hashes are in modular crypt format $2a$cost$...
+	Bcrypt = "bcrypt"
+	// Argon2id is argon2id algorithm, hashes are in PHC format $argon2id$v=19$m=...,t=...,p=...$salt$key
+	Argon2id = "argon2id"
+)
+
+var (
+	// ErrMismatch is returned when password doesn't match hash
+	ErrMismatch = errors.New("password doesn't match hash")
+	// ErrUnknownAlgorithm is returned when algorithm is not supported
+	ErrUnknownAlgorithm = errors.New("unknown hashing algorithm")
+	// ErrInvalidConfig is returned when parameters of algorithm are out of range
+	ErrInvalidConfig = errors.New("invalid hashing config")
+	// ErrInvalidHash is returned when hash can't be parsed
+	ErrInvalidHash = errors.New("invalid hash")
+)
+
+// Config is configuration of password hashing, only parameters of Algorithm are used for new hashes
+type Config struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2Time is number of passes over memory
+	Argon2Time uint32
+	// Argon2Memory is memory in KiB
+	Argon2Memory  uint32
+	Argon2Threads uint8
+	Argon2KeyLen  uint32
+	Argon2SaltLen uint32
+}
+
+// DefaultConfig returns bcrypt configuration with default cost and recommended argon2id parameters
+func DefaultConfig() *Config {
+	return &Config{
+		Algorithm:     Bcrypt,
+		BcryptCost:    bcrypt.DefaultCost,
+		Argon2Time:    1,
+		Argon2Memory:  64 * 1024,
+		Argon2Threads: 4,
+		Argon2KeyLen:  32,
+		Argon2SaltLen: 16,
+	}
+}
+
+// Hasher hashes passwords and verifies them against hashes of any supported algorithm
+type Hasher struct {
+	config *Config
+}
+
+// New creates hasher, config is validated for selected algorithm
+func New(config *Config) (*Hasher, error) {
+	switch config.Algorithm {
+	case Bcrypt:
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return nil, ErrInvalidConfig
+		}
+	case Argon2id:
+		if config.Argon2Time == 0 || config.Argon2Memory == 0 || config.Argon2Threads == 0 ||
+			config.Argon2KeyLen == 0 || config.Argon2SaltLen == 0 {
+			return nil, ErrInvalidConfig
+		}
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+
+	return &Hasher{config: config}, nil
+}
+
+// Hash returns hash of password with configured algorithm and parameters
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.config.Algorithm == Argon2id {
+		params := &argon2Params{
+			time:    h.config.Argon2Time,
+			memory:  h.config.Argon2Memory,
+			threads: h.config.Argon2Threads,
+			salt:    make([]byte, h.config.Argon2SaltLen),
+		}
+		_, err := rand.Read(params.salt)
+		if err != nil {
+			return "", err
+		}
+		params.key = argon2.IDKey([]byte(password), params.salt, params.time, params.memory, params.threads, h.config.Argon2KeyLen)
+		return params.String(), nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.config.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Compare checks password against hash of any supported algorithm, ErrMismatch is returned on wrong password
+func (h *Hasher) Compare(hash string, password string) error {
+	if strings.HasPrefix(hash, "$"+Argon2id+"$") {
+		params, err := parseArgon2(hash)
+		if err != nil {
+			return err
+		}
+		key := argon2.IDKey([]byte(password), params.salt, params.time, params.memory, params.threads, uint32(len(params.key)))
+		if subtle.ConstantTimeCompare(key, params.key) != 1 {
+			return ErrMismatch
+		}
+		return nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrMismatch
+	}
+	return err
+}
+
+// NeedsRehash reports if hash was made by other algorithm or with other parameters than configured
+func (h *Hasher) NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$"+Argon2id+"$") {
+		if h.config.Algorithm != Argon2id {
+			return true
+		}
+		params, err := parseArgon2(hash)
+		if err != nil {
+			return true
+		}
+		return params.time != h.config.Argon2Time || params.memory != h.config.Argon2Memory ||
+			params.threads != h.config.Argon2Threads || uint32(len(params.key)) != h.config.Argon2KeyLen ||
+			uint32(len(params.salt)) != h.config.Argon2SaltLen
+	}
+
+	if h.config.Algorithm != Bcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost != h.config.BcryptCost
+}
+
+// argon2Params is parsed argon2id hash
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// String encodes params in PHC format
+func (p *argon2Params) String() string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", Argon2id, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(p.salt), base64.RawStdEncoding.EncodeToString(p.key))
+}
+
+// parseArgon2 parses argon2id hash in PHC format
+func parseArgon2(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		return nil, ErrInvalidHash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return nil, ErrInvalidHash
+	}
+
+	params := &argon2Params{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+	if err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	params.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, ErrInvalidHash
+	}
+	params.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(params.key) == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	return params, nil
+}
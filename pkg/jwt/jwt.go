@@ -0,0 +1,197 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	//HS256 is HMAC SHA-256 signing algorithm
+	HS256 = "HS256"
+	//EdDSA is Ed25519 signing algorithm
+	EdDSA = "EdDSA"
+)
+
+var (
+	//ErrInvalidToken is returned when token is malformed or its signature is wrong
+	ErrInvalidToken = errors.New("invalid token")
+	//ErrExpired is returned when token is expired
+	ErrExpired = errors.New("token expired")
+	//ErrUnknownKey is returned when token is signed with unknown kid
+	ErrUnknownKey = errors.New("unknown key")
+	//ErrUnknownAlgorithm is returned when algorithm is not supported
+	ErrUnknownAlgorithm = errors.New("unknown algorithm")
+	//ErrInvalidKey is returned when key material does not fit algorithm
+	ErrInvalidKey = errors.New("invalid key")
+	//ErrNoKeys is returned when manager is created without current key
+	ErrNoKeys = errors.New("no signing key")
+)
+
+// Claims is structure of token claims, Role is space separated list of roles of subject,
+// Active is state of subject when token was issued
+type Claims struct {
+	Subject   int64  `json:"sub"`
+	Role      string `json:"role"`
+	Active    bool   `json:"active"`
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+}
+
+// Key is signing key identified by kid
+type Key struct {
+	ID        string
+	Algorithm string
+	secret    []byte
+	private   ed25519.PrivateKey
+	public    ed25519.PublicKey
+}
+
+// NewKey creates key with id for algorithm, for EdDSA secret is 32 bytes seed of private key
+func NewKey(id string, algorithm string, secret []byte) (*Key, error) {
+	key := &Key{ID: id, Algorithm: algorithm}
+	switch algorithm {
+	case HS256:
+		if len(secret) == 0 {
+			return nil, ErrInvalidKey
+		}
+		key.secret = secret
+	case EdDSA:
+		if len(secret) != ed25519.SeedSize {
+			return nil, ErrInvalidKey
+		}
+		key.private = ed25519.NewKeyFromSeed(secret)
+		key.public = key.private.Public().(ed25519.PublicKey)
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+
+	return key, nil
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// Manager signs tokens with current key and verifies tokens signed with any known key
+type Manager struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+// NewManager creates manager signing with key current, other keys are used only for verification
+func NewManager(current string, keys ...*Key) (*Manager, error) {
+	manager := &Manager{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		manager.keys[key.ID] = key
+	}
+
+	var ok bool
+	manager.current, ok = manager.keys[current]
+	if !ok {
+		return nil, ErrNoKeys
+	}
+
+	return manager, nil
+}
+
+// Sign returns signed token with claims
+func (m *Manager) Sign(claims *Claims) (string, error) {
+	headerJSON, err := json.Marshal(&header{Algorithm: m.current.Algorithm, Type: "JWT", KeyID: m.current.ID})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encode(headerJSON) + "." + encode(claimsJSON)
+	return signingInput + "." + encode(m.current.sign([]byte(signingInput))), nil
+}
+
+// Verify checks signature and expiry of token and returns its claims
+func (m *Manager) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := decode(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var h header
+	err = json.Unmarshal(headerJSON, &h)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := m.keys[h.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if h.Algorithm != key.Algorithm {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := decode(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	claimsJSON, err := decode(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := &Claims{}
+	err = json.Unmarshal(claimsJSON, claims)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpired
+	}
+
+	return claims, nil
+}
+
+// IsJWT reports whether token has form of JWT
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+func (k *Key) sign(data []byte) []byte {
+	if k.Algorithm == EdDSA {
+		return ed25519.Sign(k.private, data)
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (k *Key) verify(data []byte, signature []byte) bool {
+	if k.Algorithm == EdDSA {
+		return ed25519.Verify(k.public, data, signature)
+	}
+	return hmac.Equal(k.sign(data), signature)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(data)
+}
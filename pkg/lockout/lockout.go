@@ -0,0 +1,146 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LockedError is returned when login is blocked, RetryAfter is time left until next attempt
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed attempts, retry after %v", e.RetryAfter)
+}
+
+// Attempts is structure of failed attempts counter
+type Attempts struct {
+	Failures     int
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
+// Store keeps failed attempts counters
+type Store interface {
+	// Get returns attempts of key, zero attempts for unknown key
+	Get(ctx context.Context, key string) (*Attempts, error)
+	// Fail atomically increments failures of key, counter restarts when last failure is older than window.
+	// Blocked until time is set to block(failures)
+	Fail(ctx context.Context, key string, window time.Duration, block func(failures int) time.Time) (*Attempts, error)
+	// Reset removes counter of key
+	Reset(ctx context.Context, key string) error
+}
+
+// Config is configuration of backoff and lockout
+type Config struct {
+	// MaxFailures is number of failures after which key is locked for LockoutDuration
+	MaxFailures int
+	// BaseDelay is delay after first failure, it doubles with every next failure up to MaxDelay
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	// Window is time after last failure when counter restarts
+	Window time.Duration
+}
+
+// DefaultUserConfig returns default configuration for attempts per username
+func DefaultUserConfig() *Config {
+	return &Config{
+		MaxFailures:     5,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+}
+
+// DefaultIPConfig returns default configuration for attempts per client ip
+func DefaultIPConfig() *Config {
+	return &Config{
+		MaxFailures:     50,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+}
+
+// block returns time until which key with failures is blocked
+func (c *Config) block(failures int) time.Time {
+	now := time.Now()
+	if failures >= c.MaxFailures {
+		return now.Add(c.LockoutDuration)
+	}
+	if c.BaseDelay <= 0 || failures <= 0 {
+		return now
+	}
+
+	delay := c.BaseDelay
+	for i := 1; i < failures && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	if c.MaxDelay > 0 && delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	return now.Add(delay)
+}
+
+// Limiter limits failed login attempts per username and per client ip
+type Limiter struct {
+	store Store
+	user  *Config
+	ip    *Config
+}
+
+// NewLimiter creates limiter with counters in store
+func NewLimiter(store Store, user *Config, ip *Config) *Limiter {
+	return &Limiter{store: store, user: user, ip: ip}
+}
+
+// Check returns *LockedError if login with username from ip is blocked
+func (l *Limiter) Check(ctx context.Context, username string, ip string) error {
+	var retryAfter time.Duration
+	for _, key := range []string{userKey(username), ipKey(ip)} {
+		attempts, err := l.store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if wait := time.Until(attempts.BlockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Fail records failed login with username from ip
+func (l *Limiter) Fail(ctx context.Context, username string, ip string) error {
+	_, err := l.store.Fail(ctx, userKey(username), l.user.Window, l.user.block)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.store.Fail(ctx, ipKey(ip), l.ip.Window, l.ip.block)
+	return err
+}
+
+// Succeed resets counter of username after successful login
+func (l *Limiter) Succeed(ctx context.Context, username string) error {
+	return l.store.Reset(ctx, userKey(username))
+}
+
+// Unlock resets counter of username, it is used by admins
+func (l *Limiter) Unlock(ctx context.Context, username string) error {
+	return l.store.Reset(ctx, userKey(username))
+}
+
+func userKey(username string) string {
+	return "user:" + username
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,60 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps counters in memory, counters are lost on restart and are not shared between instances
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]*Attempts
+}
+
+// NewMemoryStore creates in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: make(map[string]*Attempts)}
+}
+
+// Get returns attempts of key
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts, ok := s.attempts[key]
+	if !ok {
+		return &Attempts{}, nil
+	}
+	copied := *attempts
+	return &copied, nil
+}
+
+// Fail increments failures of key
+func (s *MemoryStore) Fail(ctx context.Context, key string, window time.Duration, block func(failures int) time.Time) (*Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	attempts, ok := s.attempts[key]
+	if !ok || now.Sub(attempts.LastFailure) > window {
+		attempts = &Attempts{}
+		s.attempts[key] = attempts
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+	attempts.BlockedUntil = block(attempts.Failures)
+
+	copied := *attempts
+	return &copied, nil
+}
+
+// Reset removes counter of key
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
@@ -0,0 +1,76 @@
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PostgresStore keeps counters in login_attempts table
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore creates store in postgres
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+// Get returns attempts of key
+func (s *PostgresStore) Get(ctx context.Context, key string) (*Attempts, error) {
+	attempts := &Attempts{}
+	err := s.pool.QueryRow(ctx, `
+		SELECT failures, last_failure, blocked_until FROM login_attempts WHERE key = $1
+	`, key).Scan(&attempts.Failures, &attempts.LastFailure, &attempts.BlockedUntil)
+	if err == pgx.ErrNoRows {
+		return &Attempts{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// Fail increments failures of key
+func (s *PostgresStore) Fail(ctx context.Context, key string, window time.Duration, block func(failures int) time.Time) (*Attempts, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	attempts := &Attempts{LastFailure: now}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO login_attempts (key, failures, last_failure) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure = EXCLUDED.last_failure
+		RETURNING failures
+	`, key, now, now.Add(-window)).Scan(&attempts.Failures)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts.BlockedUntil = block(attempts.Failures)
+	_, err = tx.Exec(ctx, `UPDATE login_attempts SET blocked_until = $2 WHERE key = $1`, key, attempts.BlockedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// Reset removes counter of key
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM login_attempts WHERE key = $1`, key)
+	return err
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is structure of email message
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages to users
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// FileMailer appends messages to file instead of sending them, it is for local development
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileMailer creates mailer writing to file at path
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+// Send appends message to file
+func (m *FileMailer) Send(ctx context.Context, message *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n\r\n",
+		time.Now().Format(time.RFC1123Z), message.To, message.Subject, message.Body)
+	return err
+}
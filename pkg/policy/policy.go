@@ -0,0 +1,210 @@
+package policy
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Violation describes failed rule of policy
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error is returned when value violates policy, it lists every failed rule
+type Error struct {
+	Code       string       `json:"error"`
+	Violations []*Violation `json:"violations"`
+}
+
+func (e *Error) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		rules = append(rules, violation.Field+":"+violation.Rule)
+	}
+	return "policy violation: " + strings.Join(rules, ", ")
+}
+
+// Config is configuration of password, username and profile policy
+type Config struct {
+	MinPasswordLength int
+	// MaxPasswordBytes is limited by bcrypt which ignores bytes after 72th
+	MaxPasswordBytes int
+	RequireLower     bool
+	RequireUpper     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// DenylistPath is path to file with one common or breached password per line,
+	// embedded default list is used when it is empty
+	DenylistPath      string
+	MinUsernameLength int
+	MaxUsernameLength int
+	UsernamePattern   *regexp.Regexp
+	// MaxDisplayNameLength and MaxBioLength are in characters, MaxAvatarURLLength is in bytes
+	MaxDisplayNameLength int
+	MaxBioLength         int
+	MaxAvatarURLLength   int
+}
+
+// DefaultConfig returns default policy configuration
+func DefaultConfig() *Config {
+	return &Config{
+		MinPasswordLength:    8,
+		MaxPasswordBytes:     72,
+		RequireLower:         true,
+		RequireDigit:         true,
+		MinUsernameLength:    3,
+		MaxUsernameLength:    32,
+		UsernamePattern:      regexp.MustCompile(`^[\p{L}\p{N}_.\-]+$`),
+		MaxDisplayNameLength: 64,
+		MaxBioLength:         1000,
+		MaxAvatarURLLength:   2048,
+	}
+}
+
+// Policy checks passwords, usernames and profiles
+type Policy struct {
+	config   *Config
+	denylist map[string]struct{}
+}
+
+//go:embed password_denylist.txt
+var defaultDenylist string
+
+// New creates policy by config and loads denylist from config.DenylistPath or embedded default list
+func New(config *Config) (*Policy, error) {
+	policy := &Policy{config: config, denylist: make(map[string]struct{})}
+	if config.DenylistPath == "" {
+		return policy, policy.loadDenylist(strings.NewReader(defaultDenylist))
+	}
+
+	f, err := os.Open(config.DenylistPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return policy, policy.loadDenylist(f)
+}
+
+// loadDenylist adds passwords from r to denylist, empty lines and lines starting with # are skipped
+func (p *Policy) loadDenylist(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.denylist[strings.ToLower(line)] = struct{}{}
+	}
+
+	return scanner.Err()
+}
+
+// CheckPassword returns violations of password policy, username is used to deny passwords equal to it
+func (p *Policy) CheckPassword(password string, username string) []*Violation {
+	var violations []*Violation
+	add := func(rule string, message string) {
+		violations = append(violations, &Violation{Field: "password", Rule: rule, Message: message})
+	}
+
+	if password == "" {
+		add("required", "password is required")
+		return violations
+	}
+	if utf8.RuneCountInString(password) < p.config.MinPasswordLength {
+		add("min_length", "password is too short")
+	}
+	if p.config.MaxPasswordBytes > 0 && len(password) > p.config.MaxPasswordBytes {
+		add("max_length", "password is too long")
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.config.RequireLower && !lower {
+		add("lower", "password must contain lowercase letter")
+	}
+	if p.config.RequireUpper && !upper {
+		add("upper", "password must contain uppercase letter")
+	}
+	if p.config.RequireDigit && !digit {
+		add("digit", "password must contain digit")
+	}
+	if p.config.RequireSymbol && !symbol {
+		add("symbol", "password must contain symbol")
+	}
+
+	if _, ok := p.denylist[strings.ToLower(password)]; ok {
+		add("denylist", "password is too common")
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		add("username", "password must differ from username")
+	}
+
+	return violations
+}
+
+// CheckUsername returns violations of username policy
+func (p *Policy) CheckUsername(username string) []*Violation {
+	var violations []*Violation
+	add := func(rule string, message string) {
+		violations = append(violations, &Violation{Field: "username", Rule: rule, Message: message})
+	}
+
+	if username == "" {
+		add("required", "username is required")
+		return violations
+	}
+	length := utf8.RuneCountInString(username)
+	if length < p.config.MinUsernameLength {
+		add("min_length", "username is too short")
+	}
+	if p.config.MaxUsernameLength > 0 && length > p.config.MaxUsernameLength {
+		add("max_length", "username is too long")
+	}
+	if p.config.UsernamePattern != nil && !p.config.UsernamePattern.MatchString(username) {
+		add("format", "username may contain only letters, digits, '_', '.' and '-'")
+	}
+
+	return violations
+}
+
+// CheckEmail returns violations of email address format
+func (p *Policy) CheckEmail(email string) []*Violation {
+	if email == "" {
+		return []*Violation{{Field: "email", Rule: "required", Message: "email is required"}}
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return []*Violation{{Field: "email", Rule: "format", Message: "email is not valid address"}}
+	}
+	return nil
+}
+
+// NewError returns Error with violations or nil if there are no violations
+func NewError(violations ...*Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return &Error{Code: "policy_violation", Violations: violations}
+}
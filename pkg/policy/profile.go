@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"net/url"
+	"time"
+	// embedded time zone database makes time zone check independent of system files
+	_ "time/tzdata"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+// CheckDisplayName returns violations of display name policy, empty display name is allowed
+func (p *Policy) CheckDisplayName(displayName string) []*Violation {
+	if p.config.MaxDisplayNameLength > 0 && utf8.RuneCountInString(displayName) > p.config.MaxDisplayNameLength {
+		return []*Violation{{Field: "display_name", Rule: "max_length", Message: "display name is too long"}}
+	}
+	if hasControl(displayName) {
+		return []*Violation{{Field: "display_name", Rule: "format", Message: "display name may not contain control characters"}}
+	}
+	return nil
+}
+
+// CheckBio returns violations of bio policy, empty bio is allowed
+func (p *Policy) CheckBio(bio string) []*Violation {
+	if p.config.MaxBioLength > 0 && utf8.RuneCountInString(bio) > p.config.MaxBioLength {
+		return []*Violation{{Field: "bio", Rule: "max_length", Message: "bio is too long"}}
+	}
+	return nil
+}
+
+// CheckAvatarURL returns violations of avatar URL policy, avatar must be absolute http or https URL,
+// empty avatar URL is allowed
+func (p *Policy) CheckAvatarURL(avatarURL string) []*Violation {
+	if avatarURL == "" {
+		return nil
+	}
+	if p.config.MaxAvatarURLLength > 0 && len(avatarURL) > p.config.MaxAvatarURLLength {
+		return []*Violation{{Field: "avatar_url", Rule: "max_length", Message: "avatar URL is too long"}}
+	}
+
+	u, err := url.Parse(avatarURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return []*Violation{{Field: "avatar_url", Rule: "format", Message: "avatar URL must be absolute http or https URL"}}
+	}
+	return nil
+}
+
+// CheckLocale returns violations of locale, locale must be well-formed BCP 47 language tag such as "en" or "pt-BR"
+func (p *Policy) CheckLocale(locale string) []*Violation {
+	if locale == "" {
+		return []*Violation{{Field: "locale", Rule: "required", Message: "locale is required"}}
+	}
+	if _, err := language.Parse(locale); err != nil {
+		return []*Violation{{Field: "locale", Rule: "format", Message: "locale is not valid language tag"}}
+	}
+	return nil
+}
+
+// CheckTimeZone returns violations of time zone, time zone must be IANA name such as "Europe/Berlin" or "UTC"
+func (p *Policy) CheckTimeZone(timeZone string) []*Violation {
+	if timeZone == "" {
+		return []*Violation{{Field: "time_zone", Rule: "required", Message: "time zone is required"}}
+	}
+	if timeZone == "Local" {
+		return []*Violation{{Field: "time_zone", Rule: "format", Message: "time zone is not valid IANA name"}}
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return []*Violation{{Field: "time_zone", Rule: "format", Message: "time zone is not valid IANA name"}}
+	}
+	return nil
+}
+
+// hasControl checks if s contains control characters
+func hasControl(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
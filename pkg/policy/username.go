@@ -0,0 +1,20 @@
+package policy
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeUsername returns username without surrounding spaces in Unicode NFKC form,
+// usernames are stored and checked in this form
+func NormalizeUsername(username string) string {
+	return norm.NFKC.String(strings.TrimSpace(username))
+}
+
+// UsernameKey returns case-insensitive key of username, usernames with equal keys
+// such as "Brosskev" and "brosskev" belong to one user
+func UsernameKey(username string) string {
+	return norm.NFKC.String(cases.Fold().String(NormalizeUsername(username)))
+}
@@ -17,6 +17,12 @@ var (
 type RegInfo struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+// Type VerifyEmailInfo is structure for email verification
+type VerifyEmailInfo struct {
+	Token string `json:"token"`
 }
 
 type SubscribeInfo struct {
@@ -24,27 +30,248 @@ type SubscribeInfo struct {
 	CourseID int64 `json:"course_id"`
 }
 
-// Type User is structure with user data
+// Type User is structure with user data, it is internal and responses use PublicUser
 type User struct {
-	ID       int64     `json:"id"`
-	Username string    `json:"username"`
-	Password string    `json:"password"`
-	IsAdmin  bool      `json:"is_admin"`
-	Active   bool      `json:"active"`
-	Created  time.Time `json:"created"`
+	ID            int64     `json:"id"`
+	Username      string    `json:"username"`
+	Password      string    `json:"-"`
+	DisplayName   string    `json:"display_name"`
+	AvatarURL     string    `json:"avatar_url"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	IsAdmin       bool      `json:"is_admin"`
+	Active        bool      `json:"active"`
+	Created       time.Time `json:"created"`
+}
+
+// Type PublicUser is user as shown in responses, it has no password hash.
+// Account is nil when caller may see only public fields of user
+type PublicUser struct {
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+	Created     time.Time `json:"created"`
+	*Account
+}
+
+// Type Account is part of PublicUser shown only to the user and to users with user:read permission
+type Account struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	IsAdmin       bool   `json:"is_admin"`
+	Active        bool   `json:"active"`
+}
+
+// Type Profile is structure for profile of user
+type Profile struct {
+	UserID        int64  `json:"user_id"`
+	Username      string `json:"username"`
+	DisplayName   string `json:"display_name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	PendingEmail  string `json:"pending_email,omitempty"`
+	AvatarURL     string `json:"avatar_url"`
+	Bio           string `json:"bio"`
+	Locale        string `json:"locale"`
+	TimeZone      string `json:"time_zone"`
+}
+
+// Type ProfilePatch is structure for partial update of profile, nil fields are left unchanged.
+// CurrentPassword is password of user making change, it is required when email changes
+type ProfilePatch struct {
+	DisplayName     *string `json:"display_name"`
+	Email           *string `json:"email"`
+	AvatarURL       *string `json:"avatar_url"`
+	Bio             *string `json:"bio"`
+	Locale          *string `json:"locale"`
+	TimeZone        *string `json:"time_zone"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// Type UsernameAvailability is result of username availability check, Username is normalized
+type UsernameAvailability struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
 }
 
 // Type TokenInfo is structure of token info
 type TokenInfo struct {
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	UserAgent string `json:"-"`
+	IP        string `json:"-"`
+}
+
+// Type ForgotPasswordInfo is structure for password reset request
+type ForgotPasswordInfo struct {
 	Username string `json:"username"`
+}
+
+// Type ResetPasswordInfo is structure for password reset with one-time code
+type ResetPasswordInfo struct {
+	Code     string `json:"code"`
 	Password string `json:"password"`
 }
 
+// Type ChangePasswordInfo is structure for password change of authenticated user
+type ChangePasswordInfo struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Type OIDCCallbackInfo is structure of single sign-on callback
+type OIDCCallbackInfo struct {
+	Code      string
+	State     string
+	UserAgent string
+	IP        string
+}
+
+// Type RefreshInfo is structure for refresh token request
+type RefreshInfo struct {
+	RefreshToken string `json:"refresh_token"`
+	UserAgent    string `json:"-"`
+	IP           string `json:"-"`
+}
+
 // Type Token is structure for token
 type Token struct {
-	Token   string    `json:"token"`
+	Token          string    `json:"token"`
+	RefreshToken   string    `json:"refresh_token"`
+	SessionID      int64     `json:"session_id"`
+	UserID         int64     `json:"user_id"`
+	UserAgent      string    `json:"user_agent"`
+	IP             string    `json:"ip"`
+	LastUsed       time.Time `json:"last_used"`
+	Expires        time.Time `json:"expires"`
+	RefreshExpires time.Time `json:"refresh_expires"`
+	Created        time.Time `json:"created"`
+	// ImpersonatorID is id of admin for impersonation token
+	ImpersonatorID int64 `json:"impersonator_id,omitempty"`
+	// Challenge is returned instead of tokens when second factor is required
+	Challenge        string     `json:"challenge,omitempty"`
+	ChallengeExpires *time.Time `json:"challenge_expires,omitempty"`
+}
+
+// Type TwoFactorLoginInfo is structure for second step of login
+type TwoFactorLoginInfo struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// Type TwoFactorCodeInfo is structure with TOTP or recovery code
+type TwoFactorCodeInfo struct {
+	Code string `json:"code"`
+}
+
+// Type TwoFactorEnrollment is structure with new TOTP secret and its provisioning URI for QR code
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Type RecoveryCodes is structure with one-time recovery codes
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+// AuthStatus is status of token authentication
+type AuthStatus int
+
+const (
+	// AuthNoCredentials means request has no token
+	AuthNoCredentials AuthStatus = iota
+	// AuthOK means token is valid
+	AuthOK
+	// AuthInvalid means token is malformed or unknown
+	AuthInvalid
+	// AuthExpired means token is expired
+	AuthExpired
+	// AuthRevoked means token is revoked
+	AuthRevoked
+	// AuthInactive means owner of token is deactivated
+	AuthInactive
+)
+
+// Type AuthResult is result of token authentication
+type AuthResult struct {
+	UserID int64
+	// ImpersonatorID is id of admin acting as user, 0 when token is not impersonation token
+	ImpersonatorID int64
+	// Scopes are scopes of API key, nil when token is not API key
+	Scopes []string
+	// Roles are roles carried by JWT access token, nil when roles of user are read from database
+	Roles  []string
+	Status AuthStatus
+}
+
+// Type ImpersonateInfo is structure for impersonation request
+type ImpersonateInfo struct {
+	Reason    string `json:"reason"`
+	AdminID   int64  `json:"-"`
+	UserID    int64  `json:"-"`
+	UserAgent string `json:"-"`
+	IP        string `json:"-"`
+}
+
+// Type Impersonation is record of audit trail of impersonations
+type Impersonation struct {
+	ID        int64     `json:"id"`
+	AdminID   int64     `json:"admin_id"`
+	UserID    int64     `json:"user_id"`
+	SessionID int64     `json:"session_id"`
+	Reason    string    `json:"reason"`
+	Expires   time.Time `json:"expires"`
+	Created   time.Time `json:"created"`
+}
+
+// Type APIKey is structure for scoped API key of service account, plaintext key is set only on creation
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Key       string     `json:"key,omitempty"`
+	UserID    int64      `json:"user_id"`
+	Scopes    []string   `json:"scopes"`
+	CreatedBy int64      `json:"created_by"`
+	Revoked   bool       `json:"revoked"`
+	LastUsed  *time.Time `json:"last_used"`
+	Expires   time.Time  `json:"expires"`
+	Created   time.Time  `json:"created"`
+}
+
+// Type Session is structure for active session of user
+type Session struct {
+	ID        int64     `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	Current   bool      `json:"current"`
+	LastUsed  time.Time `json:"last_used"`
+	Expires   time.Time `json:"expires"`
+	Created   time.Time `json:"created"`
+}
+
+// Type Role is structure for role with its permission set
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// Type RoleInfo is structure for role assignment
+type RoleInfo struct {
+	ActorID int64  `json:"-"`
+	UserID  int64  `json:"-"`
+	Role    string `json:"role"`
+}
+
+// Type RoleChange is record of audit trail of role changes, ActorID is 0 for changes made on sign up
+type RoleChange struct {
+	ID      int64     `json:"id"`
+	ActorID int64     `json:"actor_id"`
 	UserID  int64     `json:"user_id"`
-	Expires time.Time `json:"expires"`
+	Role    string    `json:"role"`
+	Granted bool      `json:"granted"`
 	Created time.Time `json:"created"`
 }
 
@@ -52,6 +279,7 @@ type Token struct {
 type MakeAdminInfo struct {
 	ID          int64 `json:"id"`
 	AdminStatus bool  `json:"adminStatus"`
+	ActorID     int64 `json:"-"`
 }
 
 // Course is structure for course
@@ -60,5 +288,12 @@ type Course struct {
 	Name        string    `json:"name"`
 	Status      string    `json:"status"`
 	Description string    `json:"description"`
+	OwnerID     int64     `json:"owner_id"`
 	Created     time.Time `json:"created"`
 }
+
+// Type CourseStaffInfo is structure for new owner or co-instructor of course
+type CourseStaffInfo struct {
+	CourseID int64 `json:"-"`
+	UserID   int64 `json:"user_id"`
+}
@@ -0,0 +1,171 @@
+package users
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// Scopes of API keys, route accepts API key only when its policy declares scope of key
+const (
+	ScopeCoursesRead      = "courses:read"
+	ScopeCoursesWrite     = "courses:write"
+	ScopeEnrollmentsRead  = "enrollments:read"
+	ScopeEnrollmentsWrite = "enrollments:write"
+	ScopeUsersRead        = "users:read"
+)
+
+// apiKeyScopes are scopes which can be given to API key
+var apiKeyScopes = map[string]bool{
+	ScopeCoursesRead:      true,
+	ScopeCoursesWrite:     true,
+	ScopeEnrollmentsRead:  true,
+	ScopeEnrollmentsWrite: true,
+	ScopeUsersRead:        true,
+}
+
+// apiKeyPrefix tells API keys from user tokens
+const apiKeyPrefix = "goedu_"
+
+// CreateAPIKey issues API key acting as service account item.UserID within item.Scopes,
+// key expires after apiKeyTTL when item.Expires is not set
+func (s *Service) CreateAPIKey(ctx context.Context, item *types.APIKey) (*types.APIKey, int, error) {
+	for _, scope := range item.Scopes {
+		if !apiKeyScopes[scope] {
+			log.Println("CreateAPIKey unknown scope:", scope)
+			return nil, http.StatusBadRequest, ErrUnknownScope
+		}
+	}
+
+	now := time.Now()
+	if item.Expires.IsZero() {
+		item.Expires = now.Add(apiKeyTTL)
+	}
+	if !item.Expires.After(now) {
+		log.Println("CreateAPIKey expiry is in the past:", item.Expires)
+		return nil, http.StatusBadRequest, ErrInvalidExpiry
+	}
+
+	var active bool
+	err := s.pool.QueryRow(ctx, `SELECT active FROM users WHERE id = $1`, item.UserID).Scan(&active)
+	if err == pgx.ErrNoRows {
+		log.Println("CreateAPIKey s.pool.QueryRow No rows:", err)
+		return nil, http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("CreateAPIKey s.pool.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	if !active {
+		log.Println("CreateAPIKey user is deactivated:", item.UserID)
+		return nil, http.StatusForbidden, ErrInactive
+	}
+
+	key, err := generateToken(32)
+	if err != nil {
+		log.Println("CreateAPIKey generateToken error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	prefix, digest := hashToken(key)
+	err = s.pool.QueryRow(ctx, `
+		INSERT INTO api_keys (name, key_prefix, key_hash, user_id, scopes, created_by, expires, created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, item.Name, prefix, digest, item.UserID, item.Scopes, item.CreatedBy, item.Expires, now).Scan(&item.ID)
+	if err != nil {
+		log.Println("CreateAPIKey s.pool.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	item.Key = apiKeyPrefix + key
+	item.Created = now
+
+	log.Printf("CreateAPIKey admin %d created key %d for user %d with scopes %v",
+		item.CreatedBy, item.ID, item.UserID, item.Scopes)
+	return item, http.StatusOK, nil
+}
+
+// APIKeys returns all API keys without plaintext keys, newest first
+func (s *Service) APIKeys(ctx context.Context) ([]*types.APIKey, int, error) {
+	keys := []*types.APIKey{}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, name, user_id, scopes, created_by, revoked, last_used, expires, created
+		FROM api_keys
+		ORDER BY created DESC
+	`)
+	if err != nil {
+		log.Println("APIKeys s.pool.Query error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key := &types.APIKey{}
+		err := rows.Scan(&key.ID, &key.Name, &key.UserID, &key.Scopes, &key.CreatedBy, &key.Revoked,
+			&key.LastUsed, &key.Expires, &key.Created)
+		if err != nil {
+			log.Println("APIKeys rows.Scan error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, http.StatusOK, nil
+}
+
+// RevokeAPIKey revokes API key with id
+func (s *Service) RevokeAPIKey(ctx context.Context, id int64) (int, error) {
+	tag, err := s.pool.Exec(ctx, `UPDATE api_keys SET revoked = TRUE WHERE id = $1`, id)
+	if err != nil {
+		log.Println("RevokeAPIKey s.pool.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		log.Println("RevokeAPIKey key not found:", id)
+		return http.StatusNotFound, ErrAPIKeyNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
+// idByAPIKey authenticates API key and returns id of its service account with scopes of key
+func (s *Service) idByAPIKey(ctx context.Context, key string) (*types.AuthResult, error) {
+	var id int64
+	var scopes []string
+	var expires time.Time
+	var revoked, active bool
+
+	prefix, digest := hashToken(strings.TrimPrefix(key, apiKeyPrefix))
+	err := s.pool.QueryRow(ctx, `
+		UPDATE api_keys SET last_used = CURRENT_TIMESTAMP WHERE key_prefix = $1 AND key_hash = $2
+		RETURNING user_id, scopes, expires, revoked,
+			(SELECT active FROM users WHERE users.id = api_keys.user_id)
+	`, prefix, digest).Scan(&id, &scopes, &expires, &revoked, &active)
+	if err == pgx.ErrNoRows {
+		log.Println("idByAPIKey s.pool.QueryRow No rows:", err)
+		return &types.AuthResult{Status: types.AuthInvalid}, nil
+	}
+	if err != nil {
+		log.Println("idByAPIKey s.pool.QueryRow error:", err)
+		return nil, ErrInternal
+	}
+	if revoked {
+		log.Println("idByAPIKey key is revoked")
+		return &types.AuthResult{Status: types.AuthRevoked}, nil
+	}
+	if expires.Before(time.Now()) {
+		log.Println("idByAPIKey key expired at:", expires)
+		return &types.AuthResult{Status: types.AuthExpired}, nil
+	}
+	if !active {
+		log.Println("idByAPIKey user is deactivated:", id)
+		return &types.AuthResult{Status: types.AuthInactive}, nil
+	}
+
+	return &types.AuthResult{UserID: id, Scopes: scopes, Status: types.AuthOK}, nil
+}
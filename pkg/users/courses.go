@@ -0,0 +1,186 @@
+package users
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// checkCourseStaff checks that user is owner or co-instructor of course or has course:manage permission
+func (s *Service) checkCourseStaff(ctx context.Context, courseID int64, userID int64) (int, error) {
+	var staff bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT COALESCE(owner_id = $2, FALSE)
+			OR EXISTS (SELECT 1 FROM courses_instructors WHERE course_id = courses.id AND user_id = $2)
+		FROM courses WHERE id = $1
+	`, courseID, userID).Scan(&staff)
+	if err == pgx.ErrNoRows {
+		return http.StatusNotFound, ErrCourseNotFound
+	}
+	if err != nil {
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if staff {
+		return http.StatusOK, nil
+	}
+
+	return s.checkCourseManager(ctx, userID)
+}
+
+// checkCourseManager checks that user has course:manage permission
+func (s *Service) checkCourseManager(ctx context.Context, userID int64) (int, error) {
+	allowed, statusCode, err := s.HasPermission(ctx, userID, PermissionCourseManage)
+	if err != nil {
+		return statusCode, err
+	}
+	if !allowed {
+		return http.StatusForbidden, ErrNotCourseStaff
+	}
+
+	return http.StatusOK, nil
+}
+
+// TransferCourse makes item.UserID owner of course, new owner stops being co-instructor
+func (s *Service) TransferCourse(ctx context.Context, item *types.CourseStaffInfo) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("TransferCourse s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	statusCode, err := checkCourseStaffTarget(ctx, tx, item)
+	if err != nil {
+		log.Println("TransferCourse checkCourseStaffTarget error:", err)
+		return statusCode, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE courses SET owner_id = $2 WHERE id = $1`, item.CourseID, item.UserID)
+	if err != nil {
+		log.Println("TransferCourse tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM courses_instructors WHERE course_id = $1 AND user_id = $2
+	`, item.CourseID, item.UserID)
+	if err != nil {
+		log.Println("TransferCourse tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("TransferCourse tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// AddCourseInstructor makes item.UserID co-instructor of course, only owner of course
+// and users with course:manage permission can add co-instructors
+func (s *Service) AddCourseInstructor(ctx context.Context, userID int64, item *types.CourseStaffInfo) (int, error) {
+	statusCode, err := s.checkCourseOwner(ctx, item.CourseID, userID)
+	if err != nil {
+		log.Println("AddCourseInstructor s.checkCourseOwner error:", err)
+		return statusCode, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("AddCourseInstructor s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	statusCode, err = checkCourseStaffTarget(ctx, tx, item)
+	if err != nil {
+		log.Println("AddCourseInstructor checkCourseStaffTarget error:", err)
+		return statusCode, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO courses_instructors (course_id, user_id)
+		SELECT id, $2 FROM courses WHERE id = $1 AND owner_id IS DISTINCT FROM $2
+		ON CONFLICT (course_id, user_id) DO NOTHING
+	`, item.CourseID, item.UserID)
+	if err != nil {
+		log.Println("AddCourseInstructor tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("AddCourseInstructor tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// RemoveCourseInstructor removes co-instructor item.UserID of course, only owner of course
+// and users with course:manage permission can remove co-instructors
+func (s *Service) RemoveCourseInstructor(ctx context.Context, userID int64, item *types.CourseStaffInfo) (int, error) {
+	statusCode, err := s.checkCourseOwner(ctx, item.CourseID, userID)
+	if err != nil {
+		log.Println("RemoveCourseInstructor s.checkCourseOwner error:", err)
+		return statusCode, err
+	}
+
+	tag, err := s.pool.Exec(ctx, `
+		DELETE FROM courses_instructors WHERE course_id = $1 AND user_id = $2
+	`, item.CourseID, item.UserID)
+	if err != nil {
+		log.Println("RemoveCourseInstructor s.pool.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		log.Println("RemoveCourseInstructor user is not co-instructor:", item.UserID)
+		return http.StatusNotFound, ErrNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
+// checkCourseOwner checks that user is owner of course or has course:manage permission
+func (s *Service) checkCourseOwner(ctx context.Context, courseID int64, userID int64) (int, error) {
+	var owner bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT COALESCE(owner_id = $2, FALSE) FROM courses WHERE id = $1
+	`, courseID, userID).Scan(&owner)
+	if err == pgx.ErrNoRows {
+		return http.StatusNotFound, ErrCourseNotFound
+	}
+	if err != nil {
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if owner {
+		return http.StatusOK, nil
+	}
+
+	return s.checkCourseManager(ctx, userID)
+}
+
+// checkCourseStaffTarget checks that course and user of item exist, course row is locked until end of tx
+func checkCourseStaffTarget(ctx context.Context, tx pgx.Tx, item *types.CourseStaffInfo) (int, error) {
+	var courseExists, userExists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM courses WHERE id = $1 FOR UPDATE),
+			EXISTS (SELECT 1 FROM users WHERE id = $2)
+	`, item.CourseID, item.UserID).Scan(&courseExists, &userExists)
+	if err != nil {
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if !courseExists {
+		return http.StatusNotFound, ErrCourseNotFound
+	}
+	if !userExists {
+		return http.StatusNotFound, ErrNotFound
+	}
+
+	return http.StatusOK, nil
+}
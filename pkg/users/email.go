@@ -0,0 +1,192 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/mailer"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// ThrottleError is returned when action is repeated too often, RetryAfter is time left until next attempt
+type ThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("too many requests, retry after %v", e.RetryAfter)
+}
+
+// newEmailVerification saves verification token for email of user and returns it
+func newEmailVerification(ctx context.Context, tx pgx.Tx, userID int64, email string) (string, error) {
+	token, err := generateToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	_, digest := hashToken(token)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO users_email_verifications (token_hash, user_id, email, expires) VALUES ($1, $2, $3, $4)
+	`, digest, userID, email, time.Now().Add(emailVerificationTTL))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// sendEmailVerification sends verification token to email
+func (s *Service) sendEmailVerification(ctx context.Context, email string, token string) error {
+	return s.mailer.Send(ctx, &mailer.Message{
+		To:      email,
+		Subject: "GoEDU email verification",
+		Body: fmt.Sprintf("Confirm your email with token: %s\r\nor open /api/v1/verify-email?token=%s\r\nIt expires in %v.",
+			token, token, emailVerificationTTL),
+	})
+}
+
+// VerifyEmail marks email of user as verified by verification token, pending email becomes email of user then.
+// Email verified by another user first can't be verified again
+func (s *Service) VerifyEmail(ctx context.Context, item *types.VerifyEmailInfo) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("VerifyEmail s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	var email string
+	_, digest := hashToken(item.Token)
+	err = tx.QueryRow(ctx, `
+		UPDATE users_email_verifications SET used = TRUE
+		WHERE token_hash = $1 AND NOT used AND expires > CURRENT_TIMESTAMP
+		RETURNING user_id, email
+	`, digest).Scan(&userID, &email)
+	if err == pgx.ErrNoRows {
+		log.Println("VerifyEmail tx.QueryRow No rows:", err)
+		return http.StatusBadRequest, ErrInvalidCode
+	}
+	if err != nil {
+		log.Println("VerifyEmail tx.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	// email could be changed after token was sent, then token is useless.
+	// Pending email replaces verified one only now
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET email = $2, email_verified = TRUE, pending_email = NULLIF(pending_email, $2)
+		WHERE id = $1 AND (email = $2 OR pending_email = $2)
+	`, userID, email)
+	if isUniqueViolation(err, "users_email_idx") {
+		log.Println("VerifyEmail tx.Exec email taken:", err)
+		return http.StatusConflict, ErrEmailTaken
+	}
+	if err != nil {
+		log.Println("VerifyEmail tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		return http.StatusBadRequest, ErrInvalidCode
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("VerifyEmail tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// ResendEmailVerification sends new verification token to pending email of user or to unverified email,
+// it is allowed once per resendInterval and resendDailyLimit times per day
+func (s *Service) ResendEmailVerification(ctx context.Context, userID int64) (int, error) {
+	var email string
+	var verified bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT COALESCE(pending_email, email, ''), email_verified AND pending_email IS NULL FROM users WHERE id = $1
+	`, userID).Scan(&email, &verified)
+	if err == pgx.ErrNoRows {
+		log.Println("ResendEmailVerification s.pool.QueryRow No rows:", err)
+		return http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("ResendEmailVerification s.pool.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if email == "" {
+		return http.StatusUnprocessableEntity, ErrNoEmail
+	}
+	if verified {
+		return http.StatusConflict, ErrEmailVerified
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("ResendEmailVerification s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	wait, err := throttle(ctx, tx, "users_email_verifications", userID)
+	if err != nil {
+		log.Println("ResendEmailVerification throttle error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if wait > 0 {
+		return http.StatusTooManyRequests, &ThrottleError{RetryAfter: wait}
+	}
+
+	token, err := newEmailVerification(ctx, tx, userID, email)
+	if err != nil {
+		log.Println("ResendEmailVerification newEmailVerification error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("ResendEmailVerification tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = s.sendEmailVerification(ctx, email, token)
+	if err != nil {
+		log.Println("ResendEmailVerification s.sendEmailVerification error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// throttle returns time left until user may get next message recorded in table, table must have user_id and
+// created columns. Message is allowed once per resendInterval and resendDailyLimit times per day,
+// user row is locked so concurrent requests are counted one by one
+func throttle(ctx context.Context, tx pgx.Tx, table string, userID int64) (time.Duration, error) {
+	_, err := tx.Exec(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int
+	var last time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(MAX(created), 'epoch') FROM `+table+`
+		WHERE user_id = $1 AND created > $2
+	`, userID, time.Now().Add(-24*time.Hour)).Scan(&sent, &last)
+	if err != nil {
+		return 0, err
+	}
+	if wait := time.Until(last.Add(resendInterval)); wait > 0 {
+		return wait, nil
+	}
+	if sent >= resendDailyLimit {
+		return time.Until(last.Add(24 * time.Hour)), nil
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,125 @@
+package users
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// Impersonate issues time-limited token of user for admin and records it in audit trail.
+// Impersonation token has no refresh token, only admins may impersonate and admins can't be impersonated
+// whatever roles are granted user:impersonate, as impersonation gives all permissions of user
+func (s *Service) Impersonate(ctx context.Context, item *types.ImpersonateInfo) (*types.Token, int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("Impersonate s.pool.Begin error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var actorIsAdmin, isAdmin, active bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users_roles WHERE user_id = $3 AND role = $2),
+			EXISTS (SELECT 1 FROM users_roles WHERE user_id = users.id AND role = $2), active
+		FROM users WHERE id = $1 FOR SHARE
+	`, item.UserID, RoleAdmin, item.AdminID).Scan(&actorIsAdmin, &isAdmin, &active)
+	if err == pgx.ErrNoRows {
+		log.Println("Impersonate tx.QueryRow No rows:", err)
+		return nil, http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("Impersonate tx.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	if !actorIsAdmin {
+		log.Println("Impersonate actor is not admin:", item.AdminID)
+		return nil, http.StatusForbidden, ErrImpersonateNotAdmin
+	}
+	if isAdmin {
+		log.Println("Impersonate user is admin:", item.UserID)
+		return nil, http.StatusForbidden, ErrImpersonateAdmin
+	}
+	if !active {
+		log.Println("Impersonate user is deactivated:", item.UserID)
+		return nil, http.StatusForbidden, ErrInactive
+	}
+
+	now := time.Now()
+	token := &types.Token{
+		UserID:         item.UserID,
+		ImpersonatorID: item.AdminID,
+		UserAgent:      item.UserAgent,
+		IP:             item.IP,
+		LastUsed:       now,
+		Expires:        now.Add(impersonationTTL),
+		Created:        now,
+	}
+	token.Token, err = generateToken(256)
+	if err != nil {
+		log.Println("Impersonate generateToken error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	prefix, digest := hashToken(token.Token)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users_tokens (token_prefix, token_hash, user_id, impersonator_id, user_agent, ip, last_used, expires, created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, prefix, digest, token.UserID, token.ImpersonatorID, token.UserAgent, token.IP,
+		token.LastUsed, token.Expires, token.Created).Scan(&token.SessionID)
+	if err != nil {
+		log.Println("Impersonate tx.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO users_impersonations (admin_id, user_id, session_id, reason, expires, created)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, item.AdminID, item.UserID, token.SessionID, item.Reason, token.Expires, token.Created)
+	if err != nil {
+		log.Println("Impersonate tx.Exec error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("Impersonate tx.Commit error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	log.Printf("Impersonate admin %d impersonates user %d in session %d: %s",
+		item.AdminID, item.UserID, token.SessionID, item.Reason)
+	return token, http.StatusOK, nil
+}
+
+// Impersonations returns audit trail of impersonations, newest first
+func (s *Service) Impersonations(ctx context.Context) ([]*types.Impersonation, int, error) {
+	impersonations := []*types.Impersonation{}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, admin_id, user_id, session_id, reason, expires, created
+		FROM users_impersonations
+		ORDER BY created DESC
+	`)
+	if err != nil {
+		log.Println("Impersonations s.pool.Query error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		impersonation := &types.Impersonation{}
+		err := rows.Scan(&impersonation.ID, &impersonation.AdminID, &impersonation.UserID, &impersonation.SessionID,
+			&impersonation.Reason, &impersonation.Expires, &impersonation.Created)
+		if err != nil {
+			log.Println("Impersonations rows.Scan error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+		impersonations = append(impersonations, impersonation)
+	}
+
+	return impersonations, http.StatusOK, nil
+}
@@ -0,0 +1,177 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/oidc"
+	"github.com/SYSTEMTerror/GoEDU/pkg/policy"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// OIDCLogin starts single sign-on and returns URL of identity provider to redirect user to
+func (s *Service) OIDCLogin(ctx context.Context) (string, int, error) {
+	if s.oidc == nil {
+		return "", http.StatusNotFound, ErrOIDCDisabled
+	}
+
+	request, err := s.oidc.AuthCodeURL(ctx)
+	if err != nil {
+		log.Println("OIDCLogin s.oidc.AuthCodeURL error:", err)
+		return "", http.StatusBadGateway, ErrInternal
+	}
+
+	_, digest := hashToken(request.State)
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO oidc_states (state_hash, verifier, nonce, expires) VALUES ($1, $2, $3, $4)
+	`, digest, request.Verifier, request.Nonce, time.Now().Add(oidcStateTTL))
+	if err != nil {
+		log.Println("OIDCLogin s.pool.Exec error:", err)
+		return "", http.StatusInternalServerError, ErrInternal
+	}
+
+	return request.URL, http.StatusOK, nil
+}
+
+// OIDCCallback completes single sign-on, user is created on first login of external subject
+func (s *Service) OIDCCallback(ctx context.Context, item *types.OIDCCallbackInfo) (*types.Token, int, error) {
+	if s.oidc == nil {
+		return nil, http.StatusNotFound, ErrOIDCDisabled
+	}
+
+	var verifier, nonce string
+	_, digest := hashToken(item.State)
+	err := s.pool.QueryRow(ctx, `
+		DELETE FROM oidc_states WHERE state_hash = $1 AND expires > CURRENT_TIMESTAMP
+		RETURNING verifier, nonce
+	`, digest).Scan(&verifier, &nonce)
+	if err == pgx.ErrNoRows {
+		log.Println("OIDCCallback s.pool.QueryRow No rows:", err)
+		return nil, http.StatusUnauthorized, ErrInvalidToken
+	}
+	if err != nil {
+		log.Println("OIDCCallback s.pool.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	identity, err := s.oidc.Exchange(ctx, item.Code, verifier, nonce)
+	if err != nil {
+		log.Println("OIDCCallback s.oidc.Exchange error:", err)
+		return nil, http.StatusUnauthorized, ErrInvalidToken
+	}
+
+	token := &types.Token{UserAgent: item.UserAgent, IP: item.IP}
+	token.UserID, err = s.userByIdentity(ctx, identity)
+	if err != nil {
+		log.Println("OIDCCallback s.userByIdentity error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	// identity provider replaces password only, enabled second factor is still required
+	enabled, err := s.twoFactorEnabled(ctx, token.UserID)
+	if err != nil {
+		log.Println("OIDCCallback s.twoFactorEnabled error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	if enabled {
+		return s.createChallenge(ctx, token)
+	}
+
+	return s.newSession(ctx, token)
+}
+
+// userByIdentity returns id of user linked to identity, new user is provisioned if there is no link.
+// Provisioned user has no password, so local login is impossible until user resets it,
+// and gets email of identity when provider verified it and no other user has it
+func (s *Service) userByIdentity(ctx context.Context, identity *oidc.Identity) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	err = tx.QueryRow(ctx, `
+		SELECT user_id FROM users_identities WHERE issuer = $1 AND subject = $2
+	`, identity.Issuer, identity.Subject).Scan(&userID)
+	if err == nil {
+		return userID, nil
+	}
+	if err != pgx.ErrNoRows {
+		return 0, err
+	}
+
+	var email *string
+	if identity.EmailVerified && len(s.policy.CheckEmail(identity.Email)) == 0 {
+		var taken bool
+		err = tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM users WHERE lower(email) = lower($1) AND email_verified)
+		`, identity.Email).Scan(&taken)
+		if err != nil {
+			return 0, err
+		}
+		if !taken {
+			email = &identity.Email
+		}
+	}
+
+	base := s.identityUsername(identity)
+	for i := 1; userID == 0 && i <= 100; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s-%d", base, i)
+		}
+		if len(s.policy.CheckUsername(username)) != 0 {
+			base = identityUsernameFallback
+			continue
+		}
+		err = tx.QueryRow(ctx, `
+			INSERT INTO users (username, username_key, password, email, email_verified) VALUES ($1, $2, NULL, $3, $4)
+			ON CONFLICT (username_key) DO NOTHING
+			RETURNING id
+		`, username, policy.UsernameKey(username), email, email != nil).Scan(&userID)
+		if err != nil && err != pgx.ErrNoRows {
+			return 0, err
+		}
+	}
+
+	if userID == 0 {
+		return 0, fmt.Errorf("no free username for %q", base)
+	}
+
+	err = assignRole(ctx, tx, 0, userID, RoleStudent)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO users_identities (issuer, subject, user_id) VALUES ($1, $2, $3)
+	`, identity.Issuer, identity.Subject, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit(ctx)
+}
+
+// identityUsername returns first of preferred username and local part of email of identity
+// which satisfies username policy, identityUsernameFallback is used when neither does
+func (s *Service) identityUsername(identity *oidc.Identity) string {
+	candidates := []string{identity.Username}
+	if at := strings.LastIndex(identity.Email, "@"); at > 0 {
+		candidates = append(candidates, identity.Email[:at])
+	}
+
+	for _, candidate := range candidates {
+		candidate = policy.NormalizeUsername(candidate)
+		if len(s.policy.CheckUsername(candidate)) == 0 {
+			return candidate
+		}
+	}
+	return identityUsernameFallback
+}
@@ -0,0 +1,234 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/mailer"
+	"github.com/SYSTEMTerror/GoEDU/pkg/policy"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// ForgotPassword creates one-time password reset code and sends it to verified email of user, it is allowed
+// as often as email verification resend. Unknown username, user without verified email, throttling and
+// mailer failure are not reported to caller to prevent usernames enumeration
+func (s *Service) ForgotPassword(ctx context.Context, item *types.ForgotPasswordInfo) (int, error) {
+	var userID int64
+	var email string
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, CASE WHEN email_verified THEN COALESCE(email, '') ELSE '' END FROM users WHERE username_key = $1
+	`, policy.UsernameKey(item.Username)).Scan(&userID, &email)
+	if err == pgx.ErrNoRows {
+		log.Println("ForgotPassword s.pool.QueryRow No rows:", err)
+		return http.StatusOK, nil
+	}
+	if err != nil {
+		log.Println("ForgotPassword s.pool.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if email == "" {
+		log.Println("ForgotPassword user has no verified email:", userID)
+		return http.StatusOK, nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("ForgotPassword s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	wait, err := throttle(ctx, tx, "users_password_resets", userID)
+	if err != nil {
+		log.Println("ForgotPassword throttle error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if wait > 0 {
+		log.Println("ForgotPassword throttled for user:", userID)
+		return http.StatusOK, nil
+	}
+
+	code, err := generateToken(16)
+	if err != nil {
+		log.Println("ForgotPassword generateToken error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	_, digest := hashToken(code)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO users_password_resets (user_id, code_hash, expires) VALUES ($1, $2, $3)
+	`, userID, digest, time.Now().Add(resetCodeTTL))
+	if err != nil {
+		log.Println("ForgotPassword tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("ForgotPassword tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = s.mailer.Send(ctx, &mailer.Message{
+		To:      email,
+		Subject: "GoEDU password reset",
+		Body:    fmt.Sprintf("Your password reset code: %s\r\nIt expires in %v.", code, resetCodeTTL),
+	})
+	if err != nil {
+		log.Println("ForgotPassword s.mailer.Send error:", err)
+	}
+
+	return http.StatusOK, nil
+}
+
+// ResetPassword sets new password by one-time code, revokes all sessions of user and unlocks login
+// as user proved control of email, code stays unused when password violates policy
+func (s *Service) ResetPassword(ctx context.Context, item *types.ResetPasswordInfo) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("ResetPassword s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	var username, usernameKey string
+	_, digest := hashToken(item.Code)
+	err = tx.QueryRow(ctx, `
+		UPDATE users_password_resets SET used = TRUE
+		WHERE code_hash = $1 AND NOT used AND expires > CURRENT_TIMESTAMP
+		RETURNING user_id, (SELECT username FROM users WHERE users.id = users_password_resets.user_id),
+			(SELECT username_key FROM users WHERE users.id = users_password_resets.user_id)
+	`, digest).Scan(&userID, &username, &usernameKey)
+	if err == pgx.ErrNoRows {
+		log.Println("ResetPassword tx.QueryRow No rows:", err)
+		return http.StatusBadRequest, ErrInvalidCode
+	}
+	if err != nil {
+		log.Println("ResetPassword tx.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = policy.NewError(s.policy.CheckPassword(item.Password, username)...)
+	if err != nil {
+		log.Println("ResetPassword policy violation:", err)
+		return http.StatusUnprocessableEntity, err
+	}
+
+	hash, err := s.hasher.Hash(item.Password)
+	if err != nil {
+		log.Println("ResetPassword s.hasher.Hash error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET password = $2 WHERE id = $1`, userID, hash)
+	if err != nil {
+		log.Println("ResetPassword tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users_password_resets SET used = TRUE WHERE user_id = $1`, userID)
+	if err != nil {
+		log.Println("ResetPassword tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = revokeUserTokens(ctx, tx, userID, "")
+	if err != nil {
+		log.Println("ResetPassword revokeUserTokens error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("ResetPassword tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = s.limiter.Unlock(ctx, usernameKey)
+	if err != nil {
+		log.Println("ResetPassword s.limiter.Unlock error:", err)
+	}
+
+	return http.StatusOK, nil
+}
+
+// ChangePassword checks current password of user, sets new one and
+// revokes all sessions of user except session of currentToken
+func (s *Service) ChangePassword(ctx context.Context, userID int64, currentToken string, item *types.ChangePasswordInfo) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("ChangePassword s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var username, hash string
+	var hasPassword bool
+	err = tx.QueryRow(ctx, `
+		SELECT username, COALESCE(password, ''), password IS NOT NULL FROM users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&username, &hash, &hasPassword)
+	if err == pgx.ErrNoRows {
+		log.Println("ChangePassword tx.QueryRow No rows:", err)
+		return http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("ChangePassword tx.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if !hasPassword {
+		return http.StatusConflict, ErrNoPassword
+	}
+
+	err = s.hasher.Compare(hash, item.CurrentPassword)
+	if err != nil {
+		log.Println("ChangePassword s.hasher.Compare error:", err)
+		return http.StatusForbidden, ErrInvalidPassword
+	}
+
+	err = policy.NewError(s.policy.CheckPassword(item.NewPassword, username)...)
+	if err != nil {
+		log.Println("ChangePassword policy violation:", err)
+		return http.StatusUnprocessableEntity, err
+	}
+
+	newHash, err := s.hasher.Hash(item.NewPassword)
+	if err != nil {
+		log.Println("ChangePassword s.hasher.Hash error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET password = $2 WHERE id = $1`, userID, newHash)
+	if err != nil {
+		log.Println("ChangePassword tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	var family string
+	prefix, digest := hashToken(currentToken)
+	err = tx.QueryRow(ctx, `
+		SELECT family FROM users_tokens WHERE token_prefix = $1 AND token_hash = $2
+	`, prefix, digest).Scan(&family)
+	if err != nil && err != pgx.ErrNoRows {
+		log.Println("ChangePassword tx.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = revokeUserTokens(ctx, tx, userID, family)
+	if err != nil {
+		log.Println("ChangePassword revokeUserTokens error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("ChangePassword tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,181 @@
+package users
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/policy"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// Profile returns profile of user
+func (s *Service) Profile(ctx context.Context, userID int64) (*types.Profile, int, error) {
+	profile, err := scanProfile(s.pool.QueryRow(ctx, profileQuery, userID))
+	if err == pgx.ErrNoRows {
+		log.Println("Profile s.pool.QueryRow No rows:", err)
+		return nil, http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("Profile s.pool.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	return profile, http.StatusOK, nil
+}
+
+// UpdateProfile applies patch of actor to profile of user, only fields set in patch are validated and changed.
+// Changing email requires current password of actor and verification token is sent to new email.
+// Verified email is kept until new one is verified, unverified email is replaced at once
+func (s *Service) UpdateProfile(ctx context.Context, actorID int64, userID int64, patch *types.ProfilePatch) (*types.Profile, int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("UpdateProfile s.pool.Begin error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	profile, err := scanProfile(tx.QueryRow(ctx, profileQuery+` FOR UPDATE`, userID))
+	if err == pgx.ErrNoRows {
+		log.Println("UpdateProfile tx.QueryRow No rows:", err)
+		return nil, http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("UpdateProfile tx.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	var violations []*policy.Violation
+	emailChanged := false
+	email := profile.Email
+	if patch.DisplayName != nil {
+		profile.DisplayName = strings.TrimSpace(*patch.DisplayName)
+		violations = append(violations, s.policy.CheckDisplayName(profile.DisplayName)...)
+	}
+	if patch.Email != nil {
+		email = strings.TrimSpace(*patch.Email)
+		violations = append(violations, s.policy.CheckEmail(email)...)
+		emailChanged = !strings.EqualFold(email, profile.Email)
+		if !emailChanged {
+			// user returned to current email, pending one is dropped
+			profile.PendingEmail = ""
+		}
+	}
+	if patch.AvatarURL != nil {
+		profile.AvatarURL = strings.TrimSpace(*patch.AvatarURL)
+		violations = append(violations, s.policy.CheckAvatarURL(profile.AvatarURL)...)
+	}
+	if patch.Bio != nil {
+		profile.Bio = strings.TrimSpace(*patch.Bio)
+		violations = append(violations, s.policy.CheckBio(profile.Bio)...)
+	}
+	if patch.Locale != nil {
+		profile.Locale = strings.TrimSpace(*patch.Locale)
+		violations = append(violations, s.policy.CheckLocale(profile.Locale)...)
+	}
+	if patch.TimeZone != nil {
+		profile.TimeZone = strings.TrimSpace(*patch.TimeZone)
+		violations = append(violations, s.policy.CheckTimeZone(profile.TimeZone)...)
+	}
+	if emailChanged {
+		statusCode, err := s.checkPassword(ctx, tx, actorID, patch.CurrentPassword)
+		if err != nil {
+			log.Println("UpdateProfile s.checkPassword error:", err)
+			return nil, statusCode, err
+		}
+	}
+
+	err = policy.NewError(violations...)
+	if err != nil {
+		log.Println("UpdateProfile policy violation:", err)
+		return nil, http.StatusUnprocessableEntity, err
+	}
+	if emailChanged {
+		if profile.EmailVerified {
+			profile.PendingEmail = email
+		} else {
+			profile.Email = email
+			profile.PendingEmail = ""
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET display_name = $2, email = NULLIF($3, ''), email_verified = $4, pending_email = NULLIF($5, ''),
+			avatar_url = $6, bio = $7, locale = $8, time_zone = $9
+		WHERE id = $1
+	`, userID, profile.DisplayName, profile.Email, profile.EmailVerified, profile.PendingEmail, profile.AvatarURL,
+		profile.Bio, profile.Locale, profile.TimeZone)
+	if err != nil {
+		log.Println("UpdateProfile tx.Exec error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	var verification string
+	if emailChanged {
+		verification, err = newEmailVerification(ctx, tx, userID, email)
+		if err != nil {
+			log.Println("UpdateProfile newEmailVerification error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("UpdateProfile tx.Commit error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	if emailChanged {
+		err = s.sendEmailVerification(ctx, email, verification)
+		if err != nil {
+			log.Println("UpdateProfile s.sendEmailVerification error:", err)
+		}
+	}
+
+	return profile, http.StatusOK, nil
+}
+
+// checkPassword compares password with password of user locking user row, user provisioned by single sign-on
+// has no password to compare until it is reset
+func (s *Service) checkPassword(ctx context.Context, tx pgx.Tx, userID int64, password string) (int, error) {
+	var hash string
+	var hasPassword bool
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(password, ''), password IS NOT NULL FROM users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&hash, &hasPassword)
+	if err == pgx.ErrNoRows {
+		return http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("checkPassword tx.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if !hasPassword {
+		return http.StatusConflict, ErrNoPassword
+	}
+
+	err = s.hasher.Compare(hash, password)
+	if err != nil {
+		return http.StatusForbidden, ErrInvalidPassword
+	}
+	return http.StatusOK, nil
+}
+
+// profileQuery selects profile of user with id $1
+const profileQuery = `
+	SELECT id, username, display_name, COALESCE(email, ''), email_verified, COALESCE(pending_email, ''),
+		avatar_url, bio, locale, time_zone
+	FROM users WHERE id = $1`
+
+// scanProfile scans profile from row of profileQuery
+func scanProfile(row pgx.Row) (*types.Profile, error) {
+	profile := &types.Profile{}
+	err := row.Scan(&profile.UserID, &profile.Username, &profile.DisplayName, &profile.Email, &profile.EmailVerified,
+		&profile.PendingEmail, &profile.AvatarURL, &profile.Bio, &profile.Locale, &profile.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
@@ -0,0 +1,300 @@
+package users
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// Roles, their permission sets are stored in roles_permissions table
+const (
+	RoleStudent           = "student"
+	RoleInstructor        = "instructor"
+	RoleTeachingAssistant = "teaching_assistant"
+	RoleSupport           = "support"
+	RoleAdmin             = "admin"
+)
+
+// Permissions checked by handlers
+const (
+	PermissionCourseCreate      = "course:create"
+	PermissionCourseSubscribe   = "course:subscribe"
+	PermissionCourseSubscribers = "course:subscribers"
+	PermissionCourseManage      = "course:manage"
+	PermissionEnrollmentRead    = "enrollment:read"
+	PermissionUserRead          = "user:read"
+	PermissionUserEdit          = "user:edit"
+	PermissionUserUnlock        = "user:unlock"
+	PermissionUserLogout        = "user:logout"
+	PermissionUserDeactivate    = "user:deactivate"
+	PermissionUserImpersonate   = "user:impersonate"
+	PermissionRoleAssign        = "role:assign"
+	PermissionAuditRead         = "audit:read"
+	PermissionAPIKeyManage      = "apikey:manage"
+)
+
+// HasPermission checks if any role of user grants permission, when config requires
+// two-factor authentication for admins, admin role of user without enabled two-factor authentication grants nothing
+func (s *Service) HasPermission(ctx context.Context, userID int64, permission string) (bool, int, error) {
+	var allowed bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM users_roles
+			JOIN roles_permissions ON roles_permissions.role = users_roles.role
+			WHERE users_roles.user_id = $1 AND roles_permissions.permission = $2
+				AND (users_roles.role <> $3 OR NOT $4
+					OR EXISTS (SELECT 1 FROM users_totp WHERE user_id = $1 AND enabled))
+		)
+	`, userID, permission, RoleAdmin, s.config.RequireAdmin2FA).Scan(&allowed)
+	if err != nil {
+		log.Println("HasPermission s.pool.QueryRow error:", err)
+		return false, http.StatusInternalServerError, ErrInternal
+	}
+
+	return allowed, http.StatusOK, nil
+}
+
+// HasRolesPermission checks if any of roles grants permission, roles are carried by JWT access token
+// and are already filtered by two-factor requirement for admins, when roles are nil roles of user are read from database
+func (s *Service) HasRolesPermission(ctx context.Context, userID int64, roles []string, permission string) (bool, int, error) {
+	if roles == nil {
+		return s.HasPermission(ctx, userID, permission)
+	}
+
+	var allowed bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM roles_permissions WHERE role = ANY($1) AND permission = $2)
+	`, roles, permission).Scan(&allowed)
+	if err != nil {
+		log.Println("HasRolesPermission s.pool.QueryRow error:", err)
+		return false, http.StatusInternalServerError, ErrInternal
+	}
+
+	return allowed, http.StatusOK, nil
+}
+
+// Roles returns all roles with their permissions
+func (s *Service) Roles(ctx context.Context) ([]*types.Role, int, error) {
+	roles := []*types.Role{}
+	rows, err := s.pool.Query(ctx, `
+		SELECT roles.name, roles.description,
+			COALESCE(array_agg(roles_permissions.permission ORDER BY roles_permissions.permission)
+				FILTER (WHERE roles_permissions.permission IS NOT NULL), '{}')
+		FROM roles LEFT JOIN roles_permissions ON roles_permissions.role = roles.name
+		GROUP BY roles.name, roles.description
+		ORDER BY roles.name
+	`)
+	if err != nil {
+		log.Println("Roles s.pool.Query error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		role := &types.Role{}
+		err := rows.Scan(&role.Name, &role.Description, &role.Permissions)
+		if err != nil {
+			log.Println("Roles rows.Scan error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, http.StatusOK, nil
+}
+
+// UserRoles returns names of roles of user
+func (s *Service) UserRoles(ctx context.Context, userID int64) ([]string, int, error) {
+	var roles []string
+	err := s.pool.QueryRow(ctx, `
+		SELECT COALESCE(array_agg(role ORDER BY role) FILTER (WHERE role IS NOT NULL), '{}')
+		FROM users LEFT JOIN users_roles ON users_roles.user_id = users.id
+		WHERE users.id = $1
+		GROUP BY users.id
+	`, userID).Scan(&roles)
+	if err == pgx.ErrNoRows {
+		log.Println("UserRoles s.pool.QueryRow No rows:", err)
+		return nil, http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("UserRoles s.pool.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	return roles, http.StatusOK, nil
+}
+
+// AssignRole gives role to user, assigning role which user already has is not an error
+func (s *Service) AssignRole(ctx context.Context, item *types.RoleInfo) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("AssignRole s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	statusCode, err := checkRoleTarget(ctx, tx, item)
+	if err != nil {
+		log.Println("AssignRole checkRoleTarget error:", err)
+		return statusCode, err
+	}
+
+	err = assignRole(ctx, tx, item.ActorID, item.UserID, item.Role)
+	if err != nil {
+		log.Println("AssignRole assignRole error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("AssignRole tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// RevokeRole takes role from user, revoking role which user doesn't have is not an error.
+// Admin role of last active admin can't be revoked
+func (s *Service) RevokeRole(ctx context.Context, item *types.RoleInfo) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("RevokeRole s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	statusCode, err := checkRoleTarget(ctx, tx, item)
+	if err != nil {
+		log.Println("RevokeRole checkRoleTarget error:", err)
+		return statusCode, err
+	}
+
+	if item.Role == RoleAdmin {
+		statusCode, err = checkLastAdmin(ctx, tx, item.UserID)
+		if err != nil {
+			log.Println("RevokeRole checkLastAdmin error:", err)
+			return statusCode, err
+		}
+	}
+
+	err = revokeRole(ctx, tx, item.ActorID, item.UserID, item.Role)
+	if err != nil {
+		log.Println("RevokeRole revokeRole error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("RevokeRole tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// checkRoleTarget checks that user and role of item exist, user row is locked until end of tx
+func checkRoleTarget(ctx context.Context, tx pgx.Tx, item *types.RoleInfo) (int, error) {
+	var userExists, roleExists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 FOR UPDATE),
+			EXISTS (SELECT 1 FROM roles WHERE name = $2)
+	`, item.UserID, item.Role).Scan(&userExists, &roleExists)
+	if err != nil {
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if !userExists {
+		return http.StatusNotFound, ErrNotFound
+	}
+	if !roleExists {
+		return http.StatusNotFound, ErrRoleNotFound
+	}
+
+	return http.StatusOK, nil
+}
+
+// RoleChanges returns audit trail of role changes, newest first
+func (s *Service) RoleChanges(ctx context.Context) ([]*types.RoleChange, int, error) {
+	changes := []*types.RoleChange{}
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, COALESCE(actor_id, 0), user_id, role, granted, created
+		FROM users_roles_changes
+		ORDER BY created DESC, id DESC
+	`)
+	if err != nil {
+		log.Println("RoleChanges s.pool.Query error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		change := &types.RoleChange{}
+		err := rows.Scan(&change.ID, &change.ActorID, &change.UserID, &change.Role, &change.Granted, &change.Created)
+		if err != nil {
+			log.Println("RoleChanges rows.Scan error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, http.StatusOK, nil
+}
+
+// checkLastAdmin checks that some active admin remains when user stops being active admin,
+// admin role assignments are locked until end of tx so concurrent changes can't remove every admin
+func checkLastAdmin(ctx context.Context, tx pgx.Tx, userID int64) (int, error) {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM users_roles WHERE role = $1 FOR UPDATE`, RoleAdmin)
+	if err != nil {
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	// counted in separate statement to see changes committed while waiting for locks
+	var others, self int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE users.id <> $1), COUNT(*) FILTER (WHERE users.id = $1)
+		FROM users_roles JOIN users ON users.id = users_roles.user_id
+		WHERE users_roles.role = $2 AND users.active
+	`, userID, RoleAdmin).Scan(&others, &self)
+	if err != nil {
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if self > 0 && others == 0 {
+		return http.StatusConflict, ErrLastAdmin
+	}
+
+	return http.StatusOK, nil
+}
+
+// assignRole gives role to user in tx and records change made by actor, actorID is 0 for sign up
+func assignRole(ctx context.Context, tx pgx.Tx, actorID int64, userID int64, role string) error {
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO users_roles (user_id, role) VALUES ($1, $2)
+		ON CONFLICT (user_id, role) DO NOTHING
+	`, userID, role)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	return recordRoleChange(ctx, tx, actorID, userID, role, true)
+}
+
+// revokeRole takes role from user in tx and records change made by actor
+func revokeRole(ctx context.Context, tx pgx.Tx, actorID int64, userID int64, role string) error {
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM users_roles WHERE user_id = $1 AND role = $2
+	`, userID, role)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	return recordRoleChange(ctx, tx, actorID, userID, role, false)
+}
+
+// recordRoleChange adds role change to audit trail
+func recordRoleChange(ctx context.Context, tx pgx.Tx, actorID int64, userID int64, role string, granted bool) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO users_roles_changes (actor_id, user_id, role, granted) VALUES (NULLIF($1, 0), $2, $3, $4)
+	`, actorID, userID, role, granted)
+	return err
+}
@@ -3,16 +3,24 @@ package users
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/SYSTEMTerror/GoEDU/pkg/hasher"
+	"github.com/SYSTEMTerror/GoEDU/pkg/jwt"
+	"github.com/SYSTEMTerror/GoEDU/pkg/lockout"
+	"github.com/SYSTEMTerror/GoEDU/pkg/mailer"
+	"github.com/SYSTEMTerror/GoEDU/pkg/oidc"
+	"github.com/SYSTEMTerror/GoEDU/pkg/policy"
 	"github.com/SYSTEMTerror/GoEDU/pkg/types"
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -24,145 +32,793 @@ var (
 	ErrInternal = errors.New("internal error")
 	//ErrExpired is returned when token is expired
 	ErrExpired = errors.New("expired")
-	//ErrEmptyPassword is returned when password is empty
-	ErrEmptyPassword = errors.New("empty password")
+	//ErrInvalidToken is returned when token is unknown
+	ErrInvalidToken = errors.New("invalid token")
+	//ErrInvalidCode is returned when one-time code is unknown, used or expired
+	ErrInvalidCode = errors.New("invalid code")
+	//ErrTwoFactorNotEnrolled is returned when two-factor authentication is not enrolled
+	ErrTwoFactorNotEnrolled = errors.New("two-factor authentication not enrolled")
+	//ErrTwoFactorEnabled is returned when two-factor authentication is already enabled
+	ErrTwoFactorEnabled = errors.New("two-factor authentication already enabled")
+	//ErrOIDCDisabled is returned when single sign-on is not configured
+	ErrOIDCDisabled = errors.New("single sign-on disabled")
+	//ErrEmailTaken is returned when email belongs to another user
+	ErrEmailTaken = &ConflictError{Code: "email_taken", Field: "email", Message: "email is already taken"}
+	//ErrUsernameTaken is returned when username differing only in case or Unicode form belongs to another user
+	ErrUsernameTaken = &ConflictError{Code: "username_taken", Field: "username", Message: "username is already taken"}
+	//ErrEmailNotVerified is returned when action requires verified email
+	ErrEmailNotVerified = errors.New("email not verified")
+	//ErrNoPassword is returned when user provisioned by single sign-on has no password yet
+	ErrNoPassword = errors.New("no password")
+	//ErrNoEmail is returned when user has no email
+	ErrNoEmail = errors.New("no email")
+	//ErrEmailVerified is returned when email is already verified
+	ErrEmailVerified = errors.New("email already verified")
+	//ErrInactive is returned when user is deactivated
+	ErrInactive = errors.New("user deactivated")
+	//ErrImpersonateAdmin is returned when admin tries to impersonate another admin
+	ErrImpersonateAdmin = errors.New("admin can't be impersonated")
+	//ErrImpersonateNotAdmin is returned when user who is not admin tries to impersonate
+	ErrImpersonateNotAdmin = errors.New("only admin can impersonate")
+	//ErrRoleNotFound is returned when role is unknown
+	ErrRoleNotFound = errors.New("role not found")
+	//ErrCourseNotFound is returned when course is not found
+	ErrCourseNotFound = errors.New("course not found")
+	//ErrNotCourseStaff is returned when user is neither owner nor co-instructor of course
+	ErrNotCourseStaff = errors.New("not staff of course")
+	//ErrUnknownScope is returned when API key is requested with unknown scope
+	ErrUnknownScope = errors.New("unknown scope")
+	//ErrInvalidExpiry is returned when expiry is in the past
+	ErrInvalidExpiry = errors.New("invalid expiry")
+	//ErrAPIKeyNotFound is returned when API key is not found
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	//ErrLastAdmin is returned when change would leave no active admin
+	ErrLastAdmin = errors.New("last active admin")
+	//ErrTokenReused is returned when an already rotated refresh token is presented again
+	ErrTokenReused = errors.New("refresh token reused")
 )
 
+const (
+	//accessTokenTTL is lifetime of access token
+	accessTokenTTL = time.Hour
+	//refreshTokenTTL is lifetime of refresh token
+	refreshTokenTTL = 30 * 24 * time.Hour
+	//jwtAccessTokenTTL is lifetime of JWT access token. JWT is verified without database, so logout, revocation,
+	//deactivation and role changes reach it only when it expires and refresh token is refused, keep it short
+	jwtAccessTokenTTL = 5 * time.Minute
+	//resetCodeTTL is lifetime of password reset code
+	resetCodeTTL = 30 * time.Minute
+	//challengeTTL is lifetime of login challenge waiting for second factor
+	challengeTTL = 5 * time.Minute
+	//challengeMaxAttempts is number of wrong codes after which challenge is used up
+	challengeMaxAttempts = 5
+	//recoveryCodesCount is number of recovery codes generated on two-factor enrollment
+	recoveryCodesCount = 10
+	//identityUsernameFallback is username of user provisioned by single sign-on when identity has no suitable one
+	identityUsernameFallback = "user"
+	//oidcStateTTL is time for user to complete single sign-on at identity provider
+	oidcStateTTL = 10 * time.Minute
+	//emailVerificationTTL is lifetime of email verification token
+	emailVerificationTTL = 24 * time.Hour
+	//resendInterval is minimal interval between verification or password reset emails
+	resendInterval = time.Minute
+	//resendDailyLimit is maximal number of verification or password reset emails per day
+	resendDailyLimit = 5
+	//impersonationTTL is lifetime of impersonation token
+	impersonationTTL = 30 * time.Minute
+	//apiKeyTTL is default lifetime of API key
+	apiKeyTTL = 90 * 24 * time.Hour
+	//tokenPrefixLen is length of token prefix stored in plaintext for lookup
+	tokenPrefixLen = 8
+)
+
+// ConflictError is returned when unique value belongs to another user, Code is machine-readable
+type ConflictError struct {
+	Code    string `json:"error"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+//Config is configuration of users service
+type Config struct {
+	//Issuer is name of service shown in authenticator apps
+	Issuer string
+	//RequireAdmin2FA denies admin rights to admins without enabled two-factor authentication
+	RequireAdmin2FA bool
+	//RequireVerifiedEmail denies subscribing to courses until email is verified
+	RequireVerifiedEmail bool
+}
+
 //Service is a users service
 type Service struct {
-	pool *pgxpool.Pool
+	pool    *pgxpool.Pool
+	signer  *jwt.Manager
+	mailer  mailer.Mailer
+	policy  *policy.Policy
+	hasher  *hasher.Hasher
+	limiter *lockout.Limiter
+	oidc    *oidc.Provider
+	config  *Config
 }
 
-//NewService creates new users service
-func NewService(pool *pgxpool.Pool) *Service {
-	return &Service{pool: pool}
+//NewService creates new users service, if signer is not nil access tokens are issued as stateless JWT,
+//if oidc is nil single sign-on is disabled
+func NewService(pool *pgxpool.Pool, signer *jwt.Manager, mailer mailer.Mailer, policy *policy.Policy,
+	hasher *hasher.Hasher, limiter *lockout.Limiter, oidc *oidc.Provider, config *Config) *Service {
+	return &Service{pool: pool, signer: signer, mailer: mailer, policy: policy, hasher: hasher, limiter: limiter,
+		oidc: oidc, config: config}
 }
 
-// RegisterUser registers user
+// RegisterUser registers user, username is normalized and must be unique regardless of case
 func (s *Service) RegisterUser(ctx context.Context, item *types.RegInfo) (*types.User, int, error) {
 	user := &types.User{}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(item.Password), bcrypt.DefaultCost)
+	item.Username = policy.NormalizeUsername(item.Username)
+	item.Email = strings.TrimSpace(item.Email)
+	violations := s.policy.CheckUsername(item.Username)
+	violations = append(violations, s.policy.CheckPassword(item.Password, item.Username)...)
+	// email is optional unless config requires verified email
+	if item.Email != "" || s.config.RequireVerifiedEmail {
+		violations = append(violations, s.policy.CheckEmail(item.Email)...)
+	}
+	err := policy.NewError(violations...)
 	if err != nil {
-		log.Println("Save bcrypt.GenerateFromPassword Error:", err)
+		log.Println("RegisterUser policy violation:", err)
+		return nil, http.StatusUnprocessableEntity, err
+	}
+
+	hash, err := s.hasher.Hash(item.Password)
+	if err != nil {
+		log.Println("Save s.hasher.Hash Error:", err)
 		return nil, http.StatusInternalServerError, ErrInternal
 	}
 
-	item.Password = string(hash)
-	err = s.pool.QueryRow(ctx, `
-			INSERT INTO users (username, password) VALUES ($1, $2)
-			ON CONFLICT (username) DO NOTHING
-			RETURNING id, username, password, active, created
-		`, item.Username, item.Password).Scan(
-		&user.ID, &user.Username, &user.Password,
+	item.Password = hash
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("Register s.pool.Begin error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+			INSERT INTO users (username, username_key, password, email) VALUES ($1, $2, $3, NULLIF($4, ''))
+			RETURNING id, username, COALESCE(email, ''), active, created
+		`, item.Username, policy.UsernameKey(item.Username), item.Password, item.Email).Scan(
+		&user.ID, &user.Username, &user.Email,
 		&user.Active, &user.Created)
+	if isUniqueViolation(err, "users_username_key_idx") {
+		log.Println("Register tx.QueryRow username taken:", err)
+		return nil, http.StatusConflict, ErrUsernameTaken
+	}
+	if err != nil {
+		log.Println("Register tx.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	err = assignRole(ctx, tx, 0, user.ID, RoleStudent)
+	if err != nil {
+		log.Println("Register assignRole error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	var verification string
+	if user.Email != "" {
+		verification, err = newEmailVerification(ctx, tx, user.ID, user.Email)
+		if err != nil {
+			log.Println("Register newEmailVerification error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
-		log.Println("Register s.pool.QueryRow error:", err)
+		log.Println("Register tx.Commit error:", err)
 		return nil, http.StatusInternalServerError, ErrInternal
 	}
 
+	if user.Email != "" {
+		err = s.sendEmailVerification(ctx, user.Email, verification)
+		if err != nil {
+			log.Println("Register s.sendEmailVerification error:", err)
+		}
+	}
+
 	return user, http.StatusOK, nil
 }
 
+// UsernameAvailable checks if username can be registered, username violating policy gets policy error
+func (s *Service) UsernameAvailable(ctx context.Context, username string) (*types.UsernameAvailability, int, error) {
+	username = policy.NormalizeUsername(username)
+	err := policy.NewError(s.policy.CheckUsername(username)...)
+	if err != nil {
+		log.Println("UsernameAvailable policy violation:", err)
+		return nil, http.StatusUnprocessableEntity, err
+	}
+
+	var taken bool
+	err = s.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE username_key = $1)
+	`, policy.UsernameKey(username)).Scan(&taken)
+	if err != nil {
+		log.Println("UsernameAvailable s.pool.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	return &types.UsernameAvailability{Username: username, Available: !taken}, http.StatusOK, nil
+}
+
 // Token generates token for user
 func (s *Service) Token(ctx context.Context, item *types.TokenInfo) (*types.Token, int, error) {
+	// lockout counters and lookup use key, so any case of username counts for one user
+	item.Username = policy.UsernameKey(item.Username)
+	err := s.limiter.Check(ctx, item.Username, item.IP)
+	if err != nil {
+		log.Println("Token s.limiter.Check error:", err)
+		return nil, lockoutStatus(err), err
+	}
+
 	var hash string
-	token := &types.Token{}
-	err := s.pool.QueryRow(ctx, `SELECT id, password FROM users WHERE username = $1`, item.Username).Scan(&token.UserID, &hash)
+	var hasPassword, active bool
+	token := &types.Token{UserAgent: item.UserAgent, IP: item.IP}
+	err = s.pool.QueryRow(ctx, `
+		SELECT id, COALESCE(password, ''), password IS NOT NULL, active FROM users WHERE username_key = $1
+	`, item.Username).Scan(&token.UserID, &hash, &hasPassword, &active)
 	if err == pgx.ErrNoRows {
 		log.Println("Token s.pool.QueryRow error:", err)
+		s.failLogin(ctx, item)
 		return nil, http.StatusNotFound, ErrNotFound
 	}
 	if err != nil {
 		log.Println(err)
 		return nil, http.StatusInternalServerError, ErrInternal
 	}
+	if !hasPassword {
+		log.Println("Token user has no password:", token.UserID)
+		s.failLogin(ctx, item)
+		return nil, http.StatusUnauthorized, ErrInvalidPassword
+	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(item.Password))
+	err = s.hasher.Compare(hash, item.Password)
 	if err != nil {
-		log.Println("Token bcrypt.CompareHashAndPassword error:", err)
+		log.Println("Token s.hasher.Compare error:", err)
+		s.failLogin(ctx, item)
 		return nil, http.StatusUnauthorized, ErrInvalidPassword
 	}
 
-	buffer := make([]byte, 256)
-	n, err := rand.Read(buffer)
-	if n != len(buffer) || err != nil {
-		log.Println("Token rand.Read len : %w (must be 256), error: %w", n, err)
+	if s.hasher.NeedsRehash(hash) {
+		s.rehash(ctx, token.UserID, hash, item.Password)
+	}
+
+	if !active {
+		log.Println("Token user is deactivated:", token.UserID)
+		return nil, http.StatusForbidden, ErrInactive
+	}
+
+	enabled, err := s.twoFactorEnabled(ctx, token.UserID)
+	if err != nil {
+		log.Println("Token s.twoFactorEnabled error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	// failures are reset only when login completes, second factor failures count for the same username
+	if enabled {
+		return s.createChallenge(ctx, token)
+	}
+
+	token, statusCode, err := s.newSession(ctx, token)
+	if err == nil {
+		s.succeedLogin(ctx, item.Username)
+	}
+	return token, statusCode, err
+}
+
+// newSession issues access and refresh tokens of new family for token.UserID
+func (s *Service) newSession(ctx context.Context, token *types.Token) (*types.Token, int, error) {
+	family, err := generateToken(16)
+	if err != nil {
+		log.Println("newSession generateToken error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("newSession s.pool.Begin error:", err)
 		return nil, http.StatusInternalServerError, ErrInternal
 	}
+	defer tx.Rollback(ctx)
 
-	token.Token = hex.EncodeToString(buffer)
-	_, err = s.pool.Exec(ctx, `INSERT INTO users_tokens (user_id, token) VALUES ($1, $2)`, token.UserID, token.Token)
+	err = s.issueTokens(ctx, tx, token, family)
+	if err == ErrInactive {
+		log.Println("newSession issueTokens user is deactivated:", token.UserID)
+		return nil, http.StatusForbidden, ErrInactive
+	}
 	if err != nil {
-		log.Println("Token s.pool.Exec error:", err)
+		log.Println("newSession issueTokens error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("newSession tx.Commit error:", err)
 		return nil, http.StatusInternalServerError, ErrInternal
 	}
 
 	return token, http.StatusOK, nil
 }
 
-// IDByToken returns user id by token
-func (s *Service) IDByToken(ctx context.Context, token string) (int64, error) {
-	var id int64
+// rehash replaces outdated hash of user with hash of configured algorithm and cost,
+// errors are only logged as login doesn't depend on it
+func (s *Service) rehash(ctx context.Context, userID int64, oldHash string, password string) {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Println("rehash s.hasher.Hash error:", err)
+		return
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		UPDATE users SET password = $3 WHERE id = $1 AND password = $2
+	`, userID, oldHash, hash)
+	if err != nil {
+		log.Println("rehash s.pool.Exec error:", err)
+	}
+}
+
+// failLogin records failed login attempt
+func (s *Service) failLogin(ctx context.Context, item *types.TokenInfo) {
+	err := s.limiter.Fail(ctx, item.Username, item.IP)
+	if err != nil {
+		log.Println("failLogin s.limiter.Fail error:", err)
+	}
+}
+
+// succeedLogin resets failed login attempts of username key after completed login
+func (s *Service) succeedLogin(ctx context.Context, username string) {
+	err := s.limiter.Succeed(ctx, username)
+	if err != nil {
+		log.Println("succeedLogin s.limiter.Succeed error:", err)
+	}
+}
+
+// lockoutStatus returns status code for error of limiter
+func lockoutStatus(err error) int {
+	var lockedErr *lockout.LockedError
+	if errors.As(err, &lockedErr) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// UnlockUser resets failed login attempts of user with id
+func (s *Service) UnlockUser(ctx context.Context, id int64) (int, error) {
+	var usernameKey string
+	err := s.pool.QueryRow(ctx, `SELECT username_key FROM users WHERE id = $1`, id).Scan(&usernameKey)
+	if err == pgx.ErrNoRows {
+		log.Println("UnlockUser s.pool.QueryRow No rows:", err)
+		return http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("UnlockUser s.pool.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = s.limiter.Unlock(ctx, usernameKey)
+	if err != nil {
+		log.Println("UnlockUser s.limiter.Unlock error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// RefreshToken rotates refresh token: the presented one is marked as used and
+// a new access and refresh token pair of the same family is issued.
+// If already used refresh token is presented, the whole family is revoked.
+func (s *Service) RefreshToken(ctx context.Context, item *types.RefreshInfo) (*types.Token, int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("RefreshToken s.pool.Begin error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var family string
+	var used, revoked bool
 	var expires time.Time
+	prefix, digest := hashToken(item.RefreshToken)
+	token := &types.Token{UserAgent: item.UserAgent, IP: item.IP}
+	err = tx.QueryRow(ctx, `
+		SELECT user_id, family, used, revoked, expires FROM users_refresh_tokens
+		WHERE token_prefix = $1 AND token_hash = $2
+		FOR UPDATE
+	`, prefix, digest).Scan(&token.UserID, &family, &used, &revoked, &expires)
+	if err == pgx.ErrNoRows {
+		log.Println("RefreshToken tx.QueryRow No rows:", err)
+		return nil, http.StatusUnauthorized, ErrInvalidToken
+	}
+	if err != nil {
+		log.Println("RefreshToken tx.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	if used || revoked {
+		err = revokeFamily(ctx, tx, family)
+		if err != nil {
+			log.Println("RefreshToken revokeFamily error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+		err = tx.Commit(ctx)
+		if err != nil {
+			log.Println("RefreshToken tx.Commit error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+		log.Println("RefreshToken reuse detected, family revoked:", family)
+		return nil, http.StatusUnauthorized, ErrTokenReused
+	}
+
+	if expires.Before(time.Now()) {
+		log.Println("RefreshToken refresh token expired at:", expires)
+		return nil, http.StatusUnauthorized, ErrExpired
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users_refresh_tokens SET used = TRUE WHERE token_prefix = $1 AND token_hash = $2
+	`, prefix, digest)
+	if err != nil {
+		log.Println("RefreshToken tx.Exec error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	err = s.issueTokens(ctx, tx, token, family)
+	if err == ErrInactive {
+		log.Println("RefreshToken issueTokens user is deactivated:", token.UserID)
+		return nil, http.StatusForbidden, ErrInactive
+	}
+	if err != nil {
+		log.Println("RefreshToken issueTokens error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("RefreshToken tx.Commit error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	return token, http.StatusOK, nil
+}
 
-	err := s.pool.QueryRow(ctx, `SELECT user_id, expires FROM users_tokens WHERE token = $1`, token).Scan(&id, &expires)
+// issueTokens generates access and refresh tokens of family for token.UserID and saves them,
+// ErrInactive is returned for deactivated user
+func (s *Service) issueTokens(ctx context.Context, tx pgx.Tx, token *types.Token, family string) error {
+	var roles string
+	var active bool
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE((
+			SELECT string_agg(role, ' ' ORDER BY role) FROM users_roles
+			WHERE user_id = users.id AND (role <> $2 OR NOT $3
+				OR EXISTS (SELECT 1 FROM users_totp WHERE user_id = users.id AND enabled))
+		), ''), active
+		FROM users WHERE id = $1
+	`, token.UserID, RoleAdmin, s.config.RequireAdmin2FA).Scan(&roles, &active)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrInactive
+	}
+
+	now := time.Now()
+	token.Created = now
+	token.LastUsed = now
+	token.Expires = now.Add(accessTokenTTL)
+	token.RefreshExpires = now.Add(refreshTokenTTL)
+
+	if s.signer != nil {
+		token.Expires = now.Add(jwtAccessTokenTTL)
+		token.Token, err = s.signer.Sign(&jwt.Claims{
+			Subject:   token.UserID,
+			Role:      roles,
+			Active:    active,
+			ExpiresAt: token.Expires.Unix(),
+			IssuedAt:  now.Unix(),
+		})
+	} else {
+		token.Token, err = generateToken(256)
+	}
+	if err != nil {
+		return err
+	}
+	token.RefreshToken, err = generateToken(256)
+	if err != nil {
+		return err
+	}
+
+	prefix, digest := hashToken(token.Token)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users_tokens (token_prefix, token_hash, user_id, family, user_agent, ip, last_used, expires, created)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, prefix, digest, token.UserID, family, token.UserAgent, token.IP, token.LastUsed, token.Expires, token.Created).Scan(&token.SessionID)
+	if err != nil {
+		return err
+	}
+
+	prefix, digest = hashToken(token.RefreshToken)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO users_refresh_tokens (token_prefix, token_hash, user_id, family, expires, created)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, prefix, digest, token.UserID, family, token.RefreshExpires, token.Created)
+	return err
+}
+
+// revokeFamily revokes all access and refresh tokens of family
+func revokeFamily(ctx context.Context, tx pgx.Tx, family string) error {
+	_, err := tx.Exec(ctx, `UPDATE users_refresh_tokens SET revoked = TRUE WHERE family = $1`, family)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users_tokens SET revoked = TRUE WHERE family = $1`, family)
+	return err
+}
+
+// revokeUserTokens revokes all access and refresh tokens of user except tokens of keepFamily
+func revokeUserTokens(ctx context.Context, tx pgx.Tx, userID int64, keepFamily string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE users_tokens SET revoked = TRUE WHERE user_id = $1 AND (family = '' OR family <> $2)
+	`, userID, keepFamily)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users_refresh_tokens SET revoked = TRUE WHERE user_id = $1 AND family <> $2
+	`, userID, keepFamily)
+	return err
+}
+
+// isUniqueViolation checks if err is violation of unique constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+// hashToken returns lookup prefix and hex encoded SHA-256 digest of token,
+// only these are stored in database
+func hashToken(token string) (string, string) {
+	prefix := token
+	if len(prefix) > tokenPrefixLen {
+		prefix = prefix[:tokenPrefixLen]
+	}
+	digest := sha256.Sum256([]byte(token))
+	return prefix, hex.EncodeToString(digest[:])
+}
+
+// generateToken returns hex encoded random token of size bytes
+func generateToken(size int) (string, error) {
+	buffer := make([]byte, size)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buffer), nil
+}
+
+// IDByToken authenticates token and returns user id with authentication status, API keys are recognized by prefix,
+// JWT access tokens are verified by signature and their claims only, database is not read for them
+func (s *Service) IDByToken(ctx context.Context, token string) (*types.AuthResult, error) {
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		return s.idByAPIKey(ctx, token)
+	}
+
+	if s.signer != nil && jwt.IsJWT(token) {
+		claims, err := s.signer.Verify(token)
+		if err == jwt.ErrExpired {
+			log.Println("IDByToken s.signer.Verify expired:", err)
+			return &types.AuthResult{Status: types.AuthExpired}, nil
+		}
+		if err != nil {
+			log.Println("IDByToken s.signer.Verify error:", err)
+			return &types.AuthResult{Status: types.AuthInvalid}, nil
+		}
+		if !claims.Active {
+			log.Println("IDByToken user is deactivated:", claims.Subject)
+			return &types.AuthResult{Status: types.AuthInactive}, nil
+		}
+		return &types.AuthResult{UserID: claims.Subject, Roles: strings.Fields(claims.Role), Status: types.AuthOK}, nil
+	}
+
+	var id, impersonatorID int64
+	var expires time.Time
+	var revoked, active bool
+
+	prefix, digest := hashToken(token)
+	err := s.pool.QueryRow(ctx, `
+		UPDATE users_tokens SET last_used = CURRENT_TIMESTAMP WHERE token_prefix = $1 AND token_hash = $2
+		RETURNING user_id, COALESCE(impersonator_id, 0), expires, revoked,
+			(SELECT active FROM users WHERE users.id = users_tokens.user_id)
+	`, prefix, digest).Scan(&id, &impersonatorID, &expires, &revoked, &active)
 	if err == pgx.ErrNoRows {
 		log.Println("IDByToken s.pool.QueryRow No rows:", err)
-		return 0, nil
+		return &types.AuthResult{Status: types.AuthInvalid}, nil
 	}
-	if err != nil || expires.Before(time.Now()) {
+	if err != nil {
 		log.Println("IDByToken s.pool.QueryRow error:", err)
-		return 0, ErrInternal
+		return nil, ErrInternal
+	}
+	if revoked {
+		log.Println("IDByToken token is revoked")
+		return &types.AuthResult{Status: types.AuthRevoked}, nil
+	}
+	if expires.Before(time.Now()) {
+		log.Println("IDByToken token expired at:", expires)
+		return &types.AuthResult{Status: types.AuthExpired}, nil
+	}
+	if !active {
+		log.Println("IDByToken user is deactivated:", id)
+		return &types.AuthResult{Status: types.AuthInactive}, nil
 	}
 
-	return id, nil
+	return &types.AuthResult{UserID: id, ImpersonatorID: impersonatorID, Status: types.AuthOK}, nil
 }
 
-// IsAdmin checks if user is admin
-func (s *Service) IsAdmin(ctx context.Context, id int64) (bool, int, error) {
-	var isAdmin bool
-	err := s.pool.QueryRow(ctx, `SELECT is_admin FROM users WHERE id = $1`, id).Scan(&isAdmin)
+// RevokeToken revokes token and all tokens of its family
+func (s *Service) RevokeToken(ctx context.Context, token string) (int, error) {
+	var family string
+	prefix, digest := hashToken(token)
+	err := s.pool.QueryRow(ctx, `
+		SELECT family FROM users_tokens WHERE token_prefix = $1 AND token_hash = $2
+	`, prefix, digest).Scan(&family)
 	if err == pgx.ErrNoRows {
-		log.Println("IsAdmin s.pool.QueryRow No rows:", err)
-		return false, http.StatusNotFound, ErrNotFound
+		log.Println("RevokeToken s.pool.QueryRow No rows:", err)
+		return http.StatusUnauthorized, ErrInvalidToken
+	}
+	if err != nil {
+		log.Println("RevokeToken s.pool.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("RevokeToken s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users_tokens SET revoked = TRUE WHERE token_prefix = $1 AND token_hash = $2
+	`, prefix, digest)
+	if err != nil {
+		log.Println("RevokeToken tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
 	}
+
+	if family != "" {
+		err = revokeFamily(ctx, tx, family)
+		if err != nil {
+			log.Println("RevokeToken revokeFamily error:", err)
+			return http.StatusInternalServerError, ErrInternal
+		}
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
-		log.Println("IsAdmin s.pool.QueryRow error:", err)
-		return false, http.StatusInternalServerError, ErrInternal
+		log.Println("RevokeToken tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
 	}
 
-	return isAdmin, http.StatusOK, nil
+	return http.StatusOK, nil
 }
 
-// MakeAdmin makes user admin
-func (s *Service) MakeAdmin(ctx context.Context, makeAdminInfo *types.MakeAdminInfo) (int, error) {
-	_, err := s.pool.Exec(ctx, `UPDATE users SET is_admin = $2 WHERE id = $1`, makeAdminInfo.ID, makeAdminInfo.AdminStatus)
+// RevokeUserTokens revokes all access and refresh tokens of user
+func (s *Service) RevokeUserTokens(ctx context.Context, userID int64) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("RevokeUserTokens s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	err = revokeUserTokens(ctx, tx, userID, "")
 	if err != nil {
-		log.Println("MakeAdmin s.pool.Exec error:", err)
+		log.Println("RevokeUserTokens revokeUserTokens error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("RevokeUserTokens tx.Commit error:", err)
 		return http.StatusInternalServerError, ErrInternal
 	}
 
 	return http.StatusOK, nil
 }
 
-// CreateCourse creates course
-func (s *Service) CreateCourse(ctx context.Context, course *types.Course) (int, error) {
-	if course.ID == 0 {
-		_, err := s.pool.Exec(ctx, `
-			INSERT INTO courses (name, description, status) VALUES ($1, $2, $3)
-		`, course.Name, course.Description, course.Status)
+// SetActive activates or deactivates user with id, deactivation revokes all tokens of user,
+// last active admin can't be deactivated
+func (s *Service) SetActive(ctx context.Context, id int64, active bool) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("SetActive s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	if !active {
+		statusCode, err := checkLastAdmin(ctx, tx, id)
 		if err != nil {
-			log.Println("CreateCourse s.pool.Exec error:", err)
+			log.Println("SetActive checkLastAdmin error:", err)
+			return statusCode, err
+		}
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET active = $2 WHERE id = $1`, id, active)
+	if err != nil {
+		log.Println("SetActive tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if tag.RowsAffected() == 0 {
+		log.Println("SetActive user not found:", id)
+		return http.StatusNotFound, ErrNotFound
+	}
+
+	if !active {
+		err = revokeUserTokens(ctx, tx, id, "")
+		if err != nil {
+			log.Println("SetActive revokeUserTokens error:", err)
 			return http.StatusInternalServerError, ErrInternal
 		}
 	}
-	if course.ID != 0 {
-		_, err := s.pool.Exec(ctx, `
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("SetActive tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// MakeAdmin assigns or revokes admin role of user in one transaction,
+// unknown user gets 404 and revoking admin role of last active admin gets 409
+func (s *Service) MakeAdmin(ctx context.Context, makeAdminInfo *types.MakeAdminInfo) (int, error) {
+	item := &types.RoleInfo{ActorID: makeAdminInfo.ActorID, UserID: makeAdminInfo.ID, Role: RoleAdmin}
+	if makeAdminInfo.AdminStatus {
+		return s.AssignRole(ctx, item)
+	}
+	return s.RevokeRole(ctx, item)
+}
+
+// CreateCourse creates course owned by user with userID, or updates course when course.ID is set,
+// only staff of course and users with course:manage permission can update it
+func (s *Service) CreateCourse(ctx context.Context, userID int64, course *types.Course) (int, error) {
+	if course.ID == 0 {
+		err := s.pool.QueryRow(ctx, `
+			INSERT INTO courses (name, description, status, owner_id) VALUES ($1, $2, $3, $4)
+			RETURNING id, owner_id, created
+		`, course.Name, course.Description, course.Status, userID).Scan(&course.ID, &course.OwnerID, &course.Created)
+		if err != nil {
+			log.Println("CreateCourse s.pool.QueryRow error:", err)
+			return http.StatusInternalServerError, ErrInternal
+		}
+	} else {
+		statusCode, err := s.checkCourseStaff(ctx, course.ID, userID)
+		if err != nil {
+			log.Println("CreateCourse s.checkCourseStaff error:", err)
+			return statusCode, err
+		}
+
+		err = s.pool.QueryRow(ctx, `
 			UPDATE courses SET name = $1, description = $2, status = $3 WHERE id = $4
-		`, course.Name, course.Description, course.Status, course.ID)
+			RETURNING COALESCE(owner_id, 0), created
+		`, course.Name, course.Description, course.Status, course.ID).Scan(&course.OwnerID, &course.Created)
 		if err != nil {
-			log.Println("CreateCourse s.pool.Exec error:", err)
+			log.Println("CreateCourse s.pool.QueryRow error:", err)
 			return http.StatusInternalServerError, ErrInternal
 		}
 	}
@@ -170,9 +826,29 @@ func (s *Service) CreateCourse(ctx context.Context, course *types.Course) (int,
 	return http.StatusOK, nil
 }
 
-// Subscribe subscribes user to course
+// Subscribe subscribes user to course, deactivated user can't subscribe,
+// when config requires verified email user without verified email can't subscribe
 func (s *Service) Subscribe(ctx context.Context, subscribeInfo *types.SubscribeInfo) (int, error) {
-	_, err := s.pool.Exec(ctx, `
+	var active, verified bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT active, email_verified FROM users WHERE id = $1
+	`, subscribeInfo.UserID).Scan(&active, &verified)
+	if err == pgx.ErrNoRows {
+		log.Println("Subscribe s.pool.QueryRow No rows:", err)
+		return http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("Subscribe s.pool.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if !active {
+		return http.StatusForbidden, ErrInactive
+	}
+	if s.config.RequireVerifiedEmail && !verified {
+		return http.StatusForbidden, ErrEmailNotVerified
+	}
+
+	_, err = s.pool.Exec(ctx, `
 		INSERT INTO users_courses (user_id, course_id) VALUES ($1, $2)
 	`, subscribeInfo.UserID, subscribeInfo.CourseID)
 	if err != nil {
@@ -186,8 +862,11 @@ func (s *Service) Subscribe(ctx context.Context, subscribeInfo *types.SubscribeI
 // GetUserById returns user by id
 func (s *Service) GetUserByID(ctx context.Context, id int64) (*types.User, int, error) {
 	user := &types.User{}
-	err := s.pool.QueryRow(ctx, `SELECT id, username, password, is_admin, active, created FROM users WHERE id = $1`, id).Scan(
-		&user.ID, &user.Username, &user.Password, &user.IsAdmin, &user.Active, &user.Created)
+	err := s.pool.QueryRow(ctx, `SELECT id, username, display_name, avatar_url, COALESCE(email, ''), email_verified,
+		EXISTS (SELECT 1 FROM users_roles WHERE user_id = users.id AND role = 'admin'), active, created
+		FROM users WHERE id = $1`, id).Scan(
+		&user.ID, &user.Username, &user.DisplayName, &user.AvatarURL, &user.Email, &user.EmailVerified,
+		&user.IsAdmin, &user.Active, &user.Created)
 	if err == pgx.ErrNoRows {
 		log.Println("GetUserByID s.pool.QueryRow No rows:", err)
 		return nil, http.StatusNotFound, ErrNotFound
@@ -203,7 +882,10 @@ func (s *Service) GetUserByID(ctx context.Context, id int64) (*types.User, int,
 //GetAllUsers returns all users
 func (s *Service) GetAllUsers(ctx context.Context) ([]*types.User, int, error) {
 	var users []*types.User
-	rows, err := s.pool.Query(ctx, `SELECT id, username, password, is_admin, active, created FROM users`)
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, username, display_name, avatar_url, COALESCE(email, ''), email_verified,
+		EXISTS (SELECT 1 FROM users_roles WHERE user_id = users.id AND role = 'admin'), active, created FROM users
+	`)
 	if err != nil {
 		log.Println("GetAllUsers s.pool.Query error:", err)
 		return nil, http.StatusInternalServerError, ErrInternal
@@ -213,7 +895,8 @@ func (s *Service) GetAllUsers(ctx context.Context) ([]*types.User, int, error) {
 	for rows.Next() {
 		user := &types.User{}
 		err := rows.Scan(
-			&user.ID, &user.Username, &user.Password, &user.IsAdmin, &user.Active, &user.Created)
+			&user.ID, &user.Username, &user.DisplayName, &user.AvatarURL, &user.Email, &user.EmailVerified,
+			&user.IsAdmin, &user.Active, &user.Created)
 		if err != nil {
 			log.Println("GetAllUsers rows.Scan error:", err)
 			return nil, http.StatusInternalServerError, ErrInternal
@@ -224,11 +907,18 @@ func (s *Service) GetAllUsers(ctx context.Context) ([]*types.User, int, error) {
 	return users, http.StatusOK, nil
 }
 
-// CourseSubscribes returns course subscribes
-func (s *Service) CourseSubscribes(ctx context.Context, courseID int64) ([]*types.User, int, error) {
+// CourseSubscribes returns course subscribes, only staff of course and users with course:manage permission can see them
+func (s *Service) CourseSubscribes(ctx context.Context, userID int64, courseID int64) ([]*types.User, int, error) {
+	statusCode, err := s.checkCourseStaff(ctx, courseID, userID)
+	if err != nil {
+		log.Println("CourseSubscribes s.checkCourseStaff error:", err)
+		return nil, statusCode, err
+	}
+
 	var users []*types.User
 	rows, err := s.pool.Query(ctx, `
-		SELECT users.id, users.username, users.password, users.is_admin, users.active, users.created
+		SELECT users.id, users.username, users.display_name, users.avatar_url, COALESCE(users.email, ''), users.email_verified,
+			EXISTS (SELECT 1 FROM users_roles WHERE user_id = users.id AND role = 'admin'), users.active, users.created
 		FROM users
 		JOIN users_courses ON users_courses.user_id = users.id
 		WHERE users_courses.course_id = $1
@@ -242,7 +932,8 @@ func (s *Service) CourseSubscribes(ctx context.Context, courseID int64) ([]*type
 
 	for rows.Next() {
 		user := &types.User{}
-		err := rows.Scan(&user.ID, &user.Username, &user.Password, &user.IsAdmin, &user.Active, &user.Created)
+		err := rows.Scan(&user.ID, &user.Username, &user.DisplayName, &user.AvatarURL, &user.Email, &user.EmailVerified,
+			&user.IsAdmin, &user.Active, &user.Created)
 		if err != nil {
 			log.Println("CourseSubscribes rows.Scan error:", err)
 			return nil, http.StatusInternalServerError, ErrInternal
@@ -257,7 +948,7 @@ func (s *Service) CourseSubscribes(ctx context.Context, courseID int64) ([]*type
 func (s *Service) UserCourses(ctx context.Context, userID int64) ([]*types.Course, int, error) {
 	var courses []*types.Course
 	rows, err := s.pool.Query(ctx, `
-		SELECT courses.id, courses.name, courses.description, courses.status
+		SELECT courses.id, courses.name, courses.description, courses.status, COALESCE(courses.owner_id, 0), courses.created
 		FROM courses
 		JOIN users_courses ON users_courses.course_id = courses.id
 		WHERE users_courses.user_id = $1
@@ -271,7 +962,7 @@ func (s *Service) UserCourses(ctx context.Context, userID int64) ([]*types.Cours
 
 	for rows.Next() {
 		course := &types.Course{}
-		err := rows.Scan(&course.ID, &course.Name, &course.Description, &course.Status)
+		err := rows.Scan(&course.ID, &course.Name, &course.Description, &course.Status, &course.OwnerID, &course.Created)
 		if err != nil {
 			log.Println("UsersCourses rows.Scan error:", err)
 			return nil, http.StatusInternalServerError, ErrInternal
@@ -0,0 +1,98 @@
+package users
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// Sessions returns active sessions of user, one per refresh family with its latest access token,
+// session of currentToken is marked as current. Impersonation sessions are audit trail of admins
+// and aren't shown to impersonated user
+func (s *Service) Sessions(ctx context.Context, userID int64, currentToken string) ([]*types.Session, int, error) {
+	sessions := []*types.Session{}
+	_, digest := hashToken(currentToken)
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, user_agent, ip, current, last_used, expires, created FROM (
+			SELECT DISTINCT ON (session_key) id, user_agent, ip,
+				bool_or(token_hash = $2) OVER (PARTITION BY session_key) AS current,
+				max(last_used) OVER (PARTITION BY session_key) AS last_used,
+				expires,
+				min(created) OVER (PARTITION BY session_key) AS created
+			FROM (
+				SELECT *, CASE WHEN family = '' THEN id::TEXT ELSE family END AS session_key
+				FROM users_tokens
+				WHERE user_id = $1 AND impersonator_id IS NULL AND NOT revoked AND expires > CURRENT_TIMESTAMP
+			) tokens
+			ORDER BY session_key, tokens.created DESC
+		) sessions
+		ORDER BY last_used DESC
+	`, userID, digest)
+	if err != nil {
+		log.Println("Sessions s.pool.Query error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session := &types.Session{}
+		err := rows.Scan(&session.ID, &session.UserAgent, &session.IP, &session.Current,
+			&session.LastUsed, &session.Expires, &session.Created)
+		if err != nil {
+			log.Println("Sessions rows.Scan error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, http.StatusOK, nil
+}
+
+// RevokeSession revokes session with sessionID of user and all tokens of its family,
+// impersonation sessions can't be revoked by impersonated user
+func (s *Service) RevokeSession(ctx context.Context, userID int64, sessionID int64) (int, error) {
+	var family string
+	err := s.pool.QueryRow(ctx, `
+		SELECT family FROM users_tokens WHERE id = $1 AND user_id = $2 AND impersonator_id IS NULL
+	`, sessionID, userID).Scan(&family)
+	if err == pgx.ErrNoRows {
+		log.Println("RevokeSession s.pool.QueryRow No rows:", err)
+		return http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("RevokeSession s.pool.QueryRow error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("RevokeSession s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `UPDATE users_tokens SET revoked = TRUE WHERE id = $1`, sessionID)
+	if err != nil {
+		log.Println("RevokeSession tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	if family != "" {
+		err = revokeFamily(ctx, tx, family)
+		if err != nil {
+			log.Println("RevokeSession revokeFamily error:", err)
+			return http.StatusInternalServerError, ErrInternal
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("RevokeSession tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
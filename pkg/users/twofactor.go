@@ -0,0 +1,301 @@
+package users
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/totp"
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// twoFactorEnabled checks if user has enabled two-factor authentication
+func (s *Service) twoFactorEnabled(ctx context.Context, userID int64) (bool, error) {
+	var enabled bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users_totp WHERE user_id = $1 AND enabled)
+	`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// createChallenge saves login challenge waiting for second factor of token.UserID
+func (s *Service) createChallenge(ctx context.Context, token *types.Token) (*types.Token, int, error) {
+	challenge, err := generateToken(32)
+	if err != nil {
+		log.Println("createChallenge generateToken error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	expires := time.Now().Add(challengeTTL)
+	_, digest := hashToken(challenge)
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO users_login_challenges (challenge_hash, user_id, user_agent, ip, expires)
+		VALUES ($1, $2, $3, $4, $5)
+	`, digest, token.UserID, token.UserAgent, token.IP, expires)
+	if err != nil {
+		log.Println("createChallenge s.pool.Exec error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	return &types.Token{UserID: token.UserID, Challenge: challenge, ChallengeExpires: &expires}, http.StatusOK, nil
+}
+
+// VerifyTwoFactor completes login by challenge and TOTP or recovery code, wrong codes are recorded
+// as failed login attempts of user and IP of challenge, so locked out user can't guess codes with new challenges
+func (s *Service) VerifyTwoFactor(ctx context.Context, item *types.TwoFactorLoginInfo) (*types.Token, int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("VerifyTwoFactor s.pool.Begin error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var challengeID int64
+	login := &types.TokenInfo{}
+	token := &types.Token{}
+	_, digest := hashToken(item.Challenge)
+	err = tx.QueryRow(ctx, `
+		UPDATE users_login_challenges SET attempts = attempts + 1
+		WHERE challenge_hash = $1 AND NOT used AND attempts < $2 AND expires > CURRENT_TIMESTAMP
+		RETURNING id, user_id, user_agent, ip, (SELECT username_key FROM users WHERE users.id = user_id)
+	`, digest, challengeMaxAttempts).Scan(&challengeID, &token.UserID, &token.UserAgent, &token.IP, &login.Username)
+	if err == pgx.ErrNoRows {
+		log.Println("VerifyTwoFactor tx.QueryRow No rows:", err)
+		return nil, http.StatusUnauthorized, ErrInvalidToken
+	}
+	if err != nil {
+		log.Println("VerifyTwoFactor tx.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	login.IP = token.IP
+
+	err = s.limiter.Check(ctx, login.Username, login.IP)
+	if err != nil {
+		log.Println("VerifyTwoFactor s.limiter.Check error:", err)
+		return nil, lockoutStatus(err), err
+	}
+
+	ok, err := checkSecondFactor(ctx, tx, token.UserID, item.Code)
+	if err != nil {
+		log.Println("VerifyTwoFactor checkSecondFactor error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	if ok {
+		_, err = tx.Exec(ctx, `UPDATE users_login_challenges SET used = TRUE WHERE id = $1`, challengeID)
+		if err != nil {
+			log.Println("VerifyTwoFactor tx.Exec error:", err)
+			return nil, http.StatusInternalServerError, ErrInternal
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("VerifyTwoFactor tx.Commit error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	if !ok {
+		s.failLogin(ctx, login)
+		return nil, http.StatusUnauthorized, ErrInvalidCode
+	}
+
+	token, statusCode, err := s.newSession(ctx, token)
+	if err == nil {
+		s.succeedLogin(ctx, login.Username)
+	}
+	return token, statusCode, err
+}
+
+// checkSecondFactor checks TOTP code or unused recovery code of user, matched code can't be used again
+func checkSecondFactor(ctx context.Context, tx pgx.Tx, userID int64, code string) (bool, error) {
+	var secret string
+	var lastStep int64
+	err := tx.QueryRow(ctx, `
+		SELECT secret, last_step FROM users_totp WHERE user_id = $1 AND enabled FOR UPDATE
+	`, userID).Scan(&secret, &lastStep)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), 1)
+	if ok && step > lastStep {
+		_, err = tx.Exec(ctx, `UPDATE users_totp SET last_step = $2 WHERE user_id = $1`, userID, step)
+		return err == nil, err
+	}
+
+	_, digest := hashToken(code)
+	tag, err := tx.Exec(ctx, `
+		UPDATE users_recovery_codes SET used = TRUE WHERE user_id = $1 AND code_hash = $2 AND NOT used
+	`, userID, digest)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// EnrollTwoFactor creates new TOTP secret of user, it is enabled after ConfirmTwoFactor
+func (s *Service) EnrollTwoFactor(ctx context.Context, userID int64) (*types.TwoFactorEnrollment, int, error) {
+	var username string
+	var enabled bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT username, EXISTS (SELECT 1 FROM users_totp WHERE user_id = users.id AND enabled)
+		FROM users WHERE id = $1
+	`, userID).Scan(&username, &enabled)
+	if err == pgx.ErrNoRows {
+		log.Println("EnrollTwoFactor s.pool.QueryRow No rows:", err)
+		return nil, http.StatusNotFound, ErrNotFound
+	}
+	if err != nil {
+		log.Println("EnrollTwoFactor s.pool.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	if enabled {
+		return nil, http.StatusConflict, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Println("EnrollTwoFactor totp.GenerateSecret error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO users_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled = FALSE, last_step = 0
+	`, userID, secret)
+	if err != nil {
+		log.Println("EnrollTwoFactor s.pool.Exec error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	return &types.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(s.config.Issuer, username, secret),
+	}, http.StatusOK, nil
+}
+
+// ConfirmTwoFactor enables enrolled two-factor authentication by first code
+// and returns recovery codes, they are shown only once
+func (s *Service) ConfirmTwoFactor(ctx context.Context, userID int64, item *types.TwoFactorCodeInfo) (*types.RecoveryCodes, int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("ConfirmTwoFactor s.pool.Begin error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	var secret string
+	var enabled bool
+	err = tx.QueryRow(ctx, `
+		SELECT secret, enabled FROM users_totp WHERE user_id = $1 FOR UPDATE
+	`, userID).Scan(&secret, &enabled)
+	if err == pgx.ErrNoRows {
+		log.Println("ConfirmTwoFactor tx.QueryRow No rows:", err)
+		return nil, http.StatusConflict, ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		log.Println("ConfirmTwoFactor tx.QueryRow error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+	if enabled {
+		return nil, http.StatusConflict, ErrTwoFactorEnabled
+	}
+
+	step, ok := totp.Validate(secret, item.Code, time.Now(), 1)
+	if !ok {
+		return nil, http.StatusUnauthorized, ErrInvalidCode
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users_totp SET enabled = TRUE, last_step = $2 WHERE user_id = $1
+	`, userID, step)
+	if err != nil {
+		log.Println("ConfirmTwoFactor tx.Exec error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	codes, err := newRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		log.Println("ConfirmTwoFactor newRecoveryCodes error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("ConfirmTwoFactor tx.Commit error:", err)
+		return nil, http.StatusInternalServerError, ErrInternal
+	}
+
+	return codes, http.StatusOK, nil
+}
+
+// DisableTwoFactor disables two-factor authentication of user by TOTP or recovery code
+func (s *Service) DisableTwoFactor(ctx context.Context, userID int64, item *types.TwoFactorCodeInfo) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		log.Println("DisableTwoFactor s.pool.Begin error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	defer tx.Rollback(ctx)
+
+	ok, err := checkSecondFactor(ctx, tx, userID, item.Code)
+	if err != nil {
+		log.Println("DisableTwoFactor checkSecondFactor error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+	if !ok {
+		return http.StatusUnauthorized, ErrInvalidCode
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM users_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		log.Println("DisableTwoFactor tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM users_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		log.Println("DisableTwoFactor tx.Exec error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		log.Println("DisableTwoFactor tx.Commit error:", err)
+		return http.StatusInternalServerError, ErrInternal
+	}
+
+	return http.StatusOK, nil
+}
+
+// newRecoveryCodes replaces recovery codes of user with new ones
+func newRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64) (*types.RecoveryCodes, error) {
+	_, err := tx.Exec(ctx, `DELETE FROM users_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := &types.RecoveryCodes{}
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := generateToken(5)
+		if err != nil {
+			return nil, err
+		}
+
+		_, digest := hashToken(code)
+		_, err = tx.Exec(ctx, `
+			INSERT INTO users_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, digest)
+		if err != nil {
+			return nil, err
+		}
+		codes.Codes = append(codes.Codes, code)
+	}
+
+	return codes, nil
+}
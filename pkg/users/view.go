@@ -0,0 +1,51 @@
+package users
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/SYSTEMTerror/GoEDU/pkg/types"
+)
+
+// PublicUser returns view of user for responses, account fields are set only when withAccount is true
+func PublicUser(user *types.User, withAccount bool) *types.PublicUser {
+	view := &types.PublicUser{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+		Created:     user.Created,
+	}
+	if withAccount {
+		view.Account = &types.Account{
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			IsAdmin:       user.IsAdmin,
+			Active:        user.Active,
+		}
+	}
+	return view
+}
+
+// PublicUsers returns views of users for viewer, viewer with user:read permission sees account fields
+// of every user, other viewers see account fields only of themselves
+func (s *Service) PublicUsers(ctx context.Context, viewerID int64, users []*types.User) ([]*types.PublicUser, int, error) {
+	canRead, statusCode, err := s.HasPermission(ctx, viewerID, PermissionUserRead)
+	if err != nil {
+		log.Println("PublicUsers s.HasPermission error:", err)
+		return nil, statusCode, err
+	}
+
+	return PublicUsersFor(viewerID, canRead, users), http.StatusOK, nil
+}
+
+// PublicUsersFor returns views of users for viewer, account fields are set for every user when canRead is true
+// and only for viewer otherwise
+func PublicUsersFor(viewerID int64, canRead bool, users []*types.User) []*types.PublicUser {
+	views := make([]*types.PublicUser, 0, len(users))
+	for _, user := range users {
+		views = append(views, PublicUser(user, canRead || user.ID == viewerID))
+	}
+	return views
+}
@@ -1,4 +1,21 @@
+DROP TABLE api_keys;
+DROP TABLE users_impersonations;
+DROP TABLE users_email_verifications;
+DROP TABLE oidc_states;
+DROP TABLE users_identities;
+DROP TABLE users_login_challenges;
+DROP TABLE users_recovery_codes;
+DROP TABLE users_totp;
+DROP TABLE login_attempts;
+DROP TABLE users_password_resets;
+DROP TABLE users_refresh_tokens;
 DROP TABLE users_tokens;
+DROP TABLE users_roles_changes;
+DROP TABLE users_roles;
+DROP TABLE roles_permissions;
+DROP TABLE permissions;
+DROP TABLE roles;
 DROP TABLE users_courses;
-DROP TABLE users;
-DROP TABLE courses;
\ No newline at end of file
+DROP TABLE courses_instructors;
+DROP TABLE courses;
+DROP TABLE users;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- adds refresh tokens, access tokens issued by one refresh token chain share family
+ALTER TABLE users_tokens ADD COLUMN family TEXT NOT NULL DEFAULT '';
+
+--table of users_refresh_tokens
+CREATE TABLE users_refresh_tokens
+(
+    token       TEXT        NOT NULL    UNIQUE,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    family      TEXT        NOT NULL,
+    used        BOOLEAN     NOT NULL    DEFAULT FALSE,
+    revoked     BOOLEAN     NOT NULL    DEFAULT FALSE,
+    expires     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP + INTERVAL '30 days',
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
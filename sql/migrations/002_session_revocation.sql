@@ -0,0 +1,2 @@
+-- lets access tokens be revoked by logout before they expire
+ALTER TABLE users_tokens ADD COLUMN revoked BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- adds id and device metadata of sessions, existing sessions get empty user agent and ip
+ALTER TABLE users_tokens
+    ADD COLUMN id         BIGSERIAL   PRIMARY KEY,
+    ADD COLUMN user_agent TEXT        NOT NULL    DEFAULT '',
+    ADD COLUMN ip         TEXT        NOT NULL    DEFAULT '',
+    ADD COLUMN last_used  TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP;
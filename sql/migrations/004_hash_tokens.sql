@@ -0,0 +1,15 @@
+-- moves plaintext tokens of users_tokens and users_refresh_tokens
+-- to token_prefix + SHA-256 token_hash, existing sessions stay valid
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+ALTER TABLE users_tokens ADD COLUMN token_prefix TEXT;
+UPDATE users_tokens SET token_prefix = left(token, 8), token = encode(digest(token, 'sha256'), 'hex');
+ALTER TABLE users_tokens ALTER COLUMN token_prefix SET NOT NULL;
+ALTER TABLE users_tokens RENAME COLUMN token TO token_hash;
+CREATE INDEX users_tokens_token_prefix_idx ON users_tokens (token_prefix);
+
+ALTER TABLE users_refresh_tokens ADD COLUMN token_prefix TEXT;
+UPDATE users_refresh_tokens SET token_prefix = left(token, 8), token = encode(digest(token, 'sha256'), 'hex');
+ALTER TABLE users_refresh_tokens ALTER COLUMN token_prefix SET NOT NULL;
+ALTER TABLE users_refresh_tokens RENAME COLUMN token TO token_hash;
+CREATE INDEX users_refresh_tokens_token_prefix_idx ON users_refresh_tokens (token_prefix);
@@ -0,0 +1,74 @@
+-- replaces users.is_admin with roles and permissions,
+-- admins get admin role and every user gets student role
+--table of roles
+CREATE TABLE roles
+(
+    name        TEXT        PRIMARY KEY,
+    description TEXT        NOT NULL DEFAULT ''
+);
+
+--table of permissions
+CREATE TABLE permissions
+(
+    name        TEXT        PRIMARY KEY,
+    description TEXT        NOT NULL DEFAULT ''
+);
+
+--table of roles_permissions, named permission sets of roles
+CREATE TABLE roles_permissions
+(
+    role        TEXT        NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission  TEXT        NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+--table of users_roles
+CREATE TABLE users_roles
+(
+    user_id     BIGINT      NOT NULL REFERENCES users,
+    role        TEXT        NOT NULL REFERENCES roles,
+    created     TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('student', 'subscribes to courses'),
+    ('instructor', 'creates courses and sees their subscribers'),
+    ('teaching_assistant', 'helps instructors with subscribers'),
+    ('support', 'helps users with their accounts'),
+    ('admin', 'manages users and roles');
+
+INSERT INTO permissions (name, description) VALUES
+    ('course:create', 'create and update courses'),
+    ('course:subscribe', 'subscribe to courses'),
+    ('course:subscribers', 'read subscribers of courses'),
+    ('user:read', 'read users, their sessions and courses'),
+    ('user:unlock', 'unlock login of users'),
+    ('user:logout', 'revoke all sessions of users'),
+    ('user:deactivate', 'deactivate and reactivate users'),
+    ('user:impersonate', 'act as user, admin only'),
+    ('role:assign', 'assign and revoke roles'),
+    ('audit:read', 'read audit trail');
+
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('student', 'course:subscribe'),
+    ('instructor', 'course:create'),
+    ('instructor', 'course:subscribers'),
+    ('teaching_assistant', 'course:subscribers'),
+    ('support', 'user:read'),
+    ('support', 'user:unlock'),
+    ('support', 'user:logout'),
+    ('admin', 'course:create'),
+    ('admin', 'course:subscribers'),
+    ('admin', 'user:read'),
+    ('admin', 'user:unlock'),
+    ('admin', 'user:logout'),
+    ('admin', 'user:deactivate'),
+    ('admin', 'user:impersonate'),
+    ('admin', 'role:assign'),
+    ('admin', 'audit:read');
+
+INSERT INTO users_roles (user_id, role) SELECT id, 'admin' FROM users WHERE is_admin;
+INSERT INTO users_roles (user_id, role) SELECT id, 'student' FROM users;
+
+ALTER TABLE users DROP COLUMN is_admin;
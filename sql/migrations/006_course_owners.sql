@@ -0,0 +1,17 @@
+-- adds owners and co-instructors of courses, existing courses stay without owner
+-- and can be edited only with course:manage permission until ownership is transferred
+ALTER TABLE courses ADD COLUMN owner_id BIGINT REFERENCES users;
+
+CREATE TABLE courses_instructors
+(
+    course_id   BIGINT      NOT NULL REFERENCES courses,
+    user_id     BIGINT      NOT NULL REFERENCES users,
+    created     TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (course_id, user_id)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('course:manage', 'edit any course and transfer ownership');
+
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('admin', 'course:manage');
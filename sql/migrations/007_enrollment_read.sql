@@ -0,0 +1,9 @@
+-- lets staff read courses of any user, other users read only their own courses
+INSERT INTO permissions (name, description) VALUES
+    ('enrollment:read', 'read courses of any user');
+
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('instructor', 'enrollment:read'),
+    ('teaching_assistant', 'enrollment:read'),
+    ('support', 'enrollment:read'),
+    ('admin', 'enrollment:read');
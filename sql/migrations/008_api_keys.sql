@@ -0,0 +1,24 @@
+-- adds scoped API keys of service accounts, they are managed by admins with apikey:manage permission
+--table of api_keys
+CREATE TABLE api_keys
+(
+    id          BIGSERIAL   PRIMARY KEY,
+    name        TEXT        NOT NULL,
+    key_prefix  TEXT        NOT NULL,
+    key_hash    TEXT        NOT NULL    UNIQUE,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    scopes      TEXT[]      NOT NULL,
+    created_by  BIGINT      NOT NULL    REFERENCES users,
+    revoked     BOOLEAN     NOT NULL    DEFAULT FALSE,
+    last_used   TIMESTAMP,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX api_keys_key_prefix_idx ON api_keys (key_prefix);
+
+INSERT INTO permissions (name, description) VALUES
+    ('apikey:manage', 'issue and revoke API keys of service accounts');
+
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('admin', 'apikey:manage');
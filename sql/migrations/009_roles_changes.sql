@@ -0,0 +1,11 @@
+-- adds audit trail of role changes, actor_id is NULL for changes made on sign up
+--table of users_roles_changes
+CREATE TABLE users_roles_changes
+(
+    id          BIGSERIAL   PRIMARY KEY,
+    actor_id    BIGINT                  REFERENCES users,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    role        TEXT        NOT NULL    REFERENCES roles ON DELETE CASCADE,
+    granted     BOOLEAN     NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
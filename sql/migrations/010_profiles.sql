@@ -0,0 +1,13 @@
+-- adds profile fields of users and lets admins edit profiles of any user
+ALTER TABLE users
+    ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN avatar_url   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN bio          TEXT NOT NULL DEFAULT '',
+    ADD COLUMN locale       TEXT NOT NULL DEFAULT 'en',
+    ADD COLUMN time_zone    TEXT NOT NULL DEFAULT 'UTC';
+
+INSERT INTO permissions (name, description) VALUES
+    ('user:edit', 'edit profiles of users');
+
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('admin', 'user:edit');
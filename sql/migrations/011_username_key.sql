@@ -0,0 +1,5 @@
+-- adds username_key which makes usernames unique regardless of case and Unicode form.
+-- Key is case folded NFKC form of username, PostgreSQL can't reproduce Unicode case folding
+-- (ß, final sigma), so existing users get keys from cmd/usernamekeys which uses the same code as application.
+-- Run it right after this migration, users without key can't log in, then apply 013_username_key_index.sql
+ALTER TABLE users ADD COLUMN username_key TEXT;
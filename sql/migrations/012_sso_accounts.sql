@@ -0,0 +1,4 @@
+-- marks users provisioned by single sign-on with NULL password instead of empty hash
+ALTER TABLE users ALTER COLUMN password DROP NOT NULL;
+
+UPDATE users SET password = NULL WHERE password = '';
@@ -0,0 +1,7 @@
+-- makes username_key required and unique after cmd/usernamekeys filled it for existing users,
+-- unique username is replaced by unique username_key
+ALTER TABLE users ALTER COLUMN username_key SET NOT NULL;
+
+ALTER TABLE users DROP CONSTRAINT users_username_key;
+
+CREATE UNIQUE INDEX users_username_key_idx ON users (username_key);
@@ -0,0 +1,14 @@
+-- adds impersonation tokens issued by admins and their audit trail
+ALTER TABLE users_tokens ADD COLUMN impersonator_id BIGINT REFERENCES users;
+
+--table of users_impersonations, audit trail of impersonation tokens issued by admins
+CREATE TABLE users_impersonations
+(
+    id          BIGSERIAL   PRIMARY KEY,
+    admin_id    BIGINT      NOT NULL    REFERENCES users,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    session_id  BIGINT      NOT NULL    REFERENCES users_tokens,
+    reason      TEXT        NOT NULL,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,11 @@
+-- adds one-time password reset codes, only SHA-256 hashes of codes are stored
+--table of users_password_resets
+CREATE TABLE users_password_resets
+(
+    id          BIGSERIAL   PRIMARY KEY,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    code_hash   TEXT        NOT NULL    UNIQUE,
+    used        BOOLEAN     NOT NULL    DEFAULT FALSE,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,9 @@
+-- adds counters of failed logins used by postgres lockout store
+--table of login_attempts, key is "user:<username>" or "ip:<address>"
+CREATE TABLE login_attempts
+(
+    key           TEXT        PRIMARY KEY,
+    failures      INTEGER     NOT NULL    DEFAULT 0,
+    last_failure  TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP,
+    blocked_until TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,33 @@
+-- adds TOTP two-factor authentication, recovery codes and login challenges waiting for second factor
+--table of users_totp
+CREATE TABLE users_totp
+(
+    user_id     BIGINT      PRIMARY KEY REFERENCES users,
+    secret      TEXT        NOT NULL,
+    enabled     BOOLEAN     NOT NULL    DEFAULT FALSE,
+    last_step   BIGINT      NOT NULL    DEFAULT 0,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of users_recovery_codes
+CREATE TABLE users_recovery_codes
+(
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    code_hash   TEXT        NOT NULL,
+    used        BOOLEAN     NOT NULL    DEFAULT FALSE,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of users_login_challenges
+CREATE TABLE users_login_challenges
+(
+    id             BIGSERIAL   PRIMARY KEY,
+    challenge_hash TEXT        NOT NULL    UNIQUE,
+    user_id        BIGINT      NOT NULL    REFERENCES users,
+    user_agent     TEXT        NOT NULL    DEFAULT '',
+    ip             TEXT        NOT NULL    DEFAULT '',
+    attempts       INTEGER     NOT NULL    DEFAULT 0,
+    used           BOOLEAN     NOT NULL    DEFAULT FALSE,
+    expires        TIMESTAMP   NOT NULL,
+    created        TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,20 @@
+-- adds OpenID Connect single sign-on, external subjects linked to users and pending sign-on requests
+--table of users_identities, links external OpenID Connect subjects to users
+CREATE TABLE users_identities
+(
+    issuer      TEXT        NOT NULL,
+    subject     TEXT        NOT NULL,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+--table of oidc_states, pending single sign-on requests
+CREATE TABLE oidc_states
+(
+    state_hash  TEXT        PRIMARY KEY,
+    verifier    TEXT        NOT NULL,
+    nonce       TEXT        NOT NULL,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
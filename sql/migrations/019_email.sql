@@ -0,0 +1,20 @@
+-- adds email of users with verification, existing users have no email and it is unverified.
+-- Only verified emails are unique, so unverified address can't block its owner
+ALTER TABLE users
+    ADD COLUMN email          TEXT,
+    ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users SET email_verified = FALSE;
+
+CREATE UNIQUE INDEX users_email_idx ON users (lower(email)) WHERE email_verified;
+
+--table of users_email_verifications
+CREATE TABLE users_email_verifications
+(
+    token_hash  TEXT        PRIMARY KEY,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    email       TEXT        NOT NULL,
+    used        BOOLEAN     NOT NULL    DEFAULT FALSE,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- adds pending email of users, changed email waits there until it is verified and verified email is kept meanwhile
+ALTER TABLE users
+    ADD COLUMN pending_email TEXT;
@@ -1,3 +1,29 @@
+-- table of users
+CREATE TABLE users 
+(
+    id             BIGSERIAL   PRIMARY KEY,
+    username       TEXT        NOT NULL,
+    -- username_key is case folded NFKC form of username, usernames are unique regardless of case
+    username_key   TEXT        NOT NULL,
+    -- password is NULL for user provisioned by single sign-on until user resets it
+    password       TEXT,
+    email          TEXT,
+    email_verified BOOLEAN     NOT NULL DEFAULT FALSE,
+    -- pending_email is new email waiting for verification, verified email is kept until then
+    pending_email  TEXT,
+    display_name   TEXT        NOT NULL DEFAULT '',
+    avatar_url     TEXT        NOT NULL DEFAULT '',
+    bio            TEXT        NOT NULL DEFAULT '',
+    locale         TEXT        NOT NULL DEFAULT 'en',
+    time_zone      TEXT        NOT NULL DEFAULT 'UTC',
+    active         BOOLEAN     NOT NULL DEFAULT TRUE,
+    created        TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- only verified emails are unique, so unverified address can't block its owner
+CREATE UNIQUE INDEX users_email_idx ON users (lower(email)) WHERE email_verified;
+CREATE UNIQUE INDEX users_username_key_idx ON users (username_key);
+
 --table of courses
 CREATE TABLE courses
 (
@@ -5,18 +31,17 @@ CREATE TABLE courses
     name        TEXT        NOT NULL,
     description TEXT        NOT NULL DEFAULT '',
     status      TEXT        NOT NULL DEFAULT 'Not Started',
+    owner_id    BIGINT      REFERENCES users,
     created     TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
--- table of users
-CREATE TABLE users 
+--table of courses_instructors, co-instructors and teaching assistants of courses
+CREATE TABLE courses_instructors
 (
-    id       BIGSERIAL   PRIMARY KEY,
-    username TEXT        NOT NULL UNIQUE,
-    password TEXT        NOT NULL,
-    is_admin BOOLEAN     NOT NULL DEFAULT FALSE,
-    active   BOOLEAN     NOT NULL DEFAULT TRUE,
-    created  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+    course_id   BIGINT      NOT NULL REFERENCES courses,
+    user_id     BIGINT      NOT NULL REFERENCES users,
+    created     TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (course_id, user_id)
 );
 
 -- table of users_courses
@@ -27,11 +52,240 @@ CREATE TABLE users_courses
     created     TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+--table of roles
+CREATE TABLE roles
+(
+    name        TEXT        PRIMARY KEY,
+    description TEXT        NOT NULL DEFAULT ''
+);
+
+--table of permissions
+CREATE TABLE permissions
+(
+    name        TEXT        PRIMARY KEY,
+    description TEXT        NOT NULL DEFAULT ''
+);
+
+--table of roles_permissions, named permission sets of roles
+CREATE TABLE roles_permissions
+(
+    role        TEXT        NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission  TEXT        NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+--table of users_roles
+CREATE TABLE users_roles
+(
+    user_id     BIGINT      NOT NULL REFERENCES users,
+    role        TEXT        NOT NULL REFERENCES roles,
+    created     TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('student', 'subscribes to courses'),
+    ('instructor', 'creates courses and sees their subscribers'),
+    ('teaching_assistant', 'helps instructors with subscribers'),
+    ('support', 'helps users with their accounts'),
+    ('admin', 'manages users and roles');
+
+INSERT INTO permissions (name, description) VALUES
+    ('course:create', 'create courses and update own courses'),
+    ('course:subscribe', 'subscribe to courses'),
+    ('course:subscribers', 'read subscribers of own courses'),
+    ('course:manage', 'edit any course and transfer ownership'),
+    ('enrollment:read', 'read courses of any user'),
+    ('user:read', 'read users, their sessions and courses'),
+    ('user:edit', 'edit profiles of users'),
+    ('user:unlock', 'unlock login of users'),
+    ('user:logout', 'revoke all sessions of users'),
+    ('user:deactivate', 'deactivate and reactivate users'),
+    ('user:impersonate', 'act as user, admin only'),
+    ('role:assign', 'assign and revoke roles'),
+    ('audit:read', 'read audit trail'),
+    ('apikey:manage', 'issue and revoke API keys of service accounts');
+
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('student', 'course:subscribe'),
+    ('instructor', 'course:create'),
+    ('instructor', 'course:subscribers'),
+    ('instructor', 'enrollment:read'),
+    ('teaching_assistant', 'course:subscribers'),
+    ('teaching_assistant', 'enrollment:read'),
+    ('support', 'enrollment:read'),
+    ('support', 'user:read'),
+    ('support', 'user:unlock'),
+    ('support', 'user:logout'),
+    ('admin', 'course:create'),
+    ('admin', 'course:subscribers'),
+    ('admin', 'course:manage'),
+    ('admin', 'enrollment:read'),
+    ('admin', 'user:read'),
+    ('admin', 'user:edit'),
+    ('admin', 'user:unlock'),
+    ('admin', 'user:logout'),
+    ('admin', 'user:deactivate'),
+    ('admin', 'user:impersonate'),
+    ('admin', 'role:assign'),
+    ('admin', 'audit:read'),
+    ('admin', 'apikey:manage');
+
+--table of users_roles_changes, audit trail of role changes, actor_id is NULL for changes made on sign up
+CREATE TABLE users_roles_changes
+(
+    id          BIGSERIAL   PRIMARY KEY,
+    actor_id    BIGINT                  REFERENCES users,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    role        TEXT        NOT NULL    REFERENCES roles ON DELETE CASCADE,
+    granted     BOOLEAN     NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
 --table of users_tokens
 CREATE TABLE users_tokens
 (
-    token       TEXT        NOT NULL    UNIQUE,
+    id           BIGSERIAL   PRIMARY KEY,
+    token_prefix TEXT        NOT NULL,
+    token_hash   TEXT        NOT NULL    UNIQUE,
+    user_id      BIGINT      NOT NULL    REFERENCES users,
+    impersonator_id BIGINT               REFERENCES users,
+    family       TEXT        NOT NULL    DEFAULT '',
+    revoked      BOOLEAN     NOT NULL    DEFAULT FALSE,
+    user_agent   TEXT        NOT NULL    DEFAULT '',
+    ip           TEXT        NOT NULL    DEFAULT '',
+    last_used    TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP,
+    expires      TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 hour',
+    created      TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX users_tokens_token_prefix_idx ON users_tokens (token_prefix);
+
+--table of users_refresh_tokens
+CREATE TABLE users_refresh_tokens
+(
+    token_prefix TEXT        NOT NULL,
+    token_hash   TEXT        NOT NULL    UNIQUE,
+    user_id      BIGINT      NOT NULL    REFERENCES users,
+    family       TEXT        NOT NULL,
+    used         BOOLEAN     NOT NULL    DEFAULT FALSE,
+    revoked      BOOLEAN     NOT NULL    DEFAULT FALSE,
+    expires      TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP + INTERVAL '30 days',
+    created      TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX users_refresh_tokens_token_prefix_idx ON users_refresh_tokens (token_prefix);
+
+--table of users_password_resets
+CREATE TABLE users_password_resets
+(
+    id          BIGSERIAL   PRIMARY KEY,
     user_id     BIGINT      NOT NULL    REFERENCES users,
-    expires     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 hour',
+    code_hash   TEXT        NOT NULL    UNIQUE,
+    used        BOOLEAN     NOT NULL    DEFAULT FALSE,
+    expires     TIMESTAMP   NOT NULL,
     created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
 );
+
+--table of login_attempts, key is "user:<username>" or "ip:<address>"
+CREATE TABLE login_attempts
+(
+    key           TEXT        PRIMARY KEY,
+    failures      INTEGER     NOT NULL    DEFAULT 0,
+    last_failure  TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP,
+    blocked_until TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of users_totp
+CREATE TABLE users_totp
+(
+    user_id     BIGINT      PRIMARY KEY REFERENCES users,
+    secret      TEXT        NOT NULL,
+    enabled     BOOLEAN     NOT NULL    DEFAULT FALSE,
+    last_step   BIGINT      NOT NULL    DEFAULT 0,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of users_recovery_codes
+CREATE TABLE users_recovery_codes
+(
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    code_hash   TEXT        NOT NULL,
+    used        BOOLEAN     NOT NULL    DEFAULT FALSE,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of users_login_challenges
+CREATE TABLE users_login_challenges
+(
+    id             BIGSERIAL   PRIMARY KEY,
+    challenge_hash TEXT        NOT NULL    UNIQUE,
+    user_id        BIGINT      NOT NULL    REFERENCES users,
+    user_agent     TEXT        NOT NULL    DEFAULT '',
+    ip             TEXT        NOT NULL    DEFAULT '',
+    attempts       INTEGER     NOT NULL    DEFAULT 0,
+    used           BOOLEAN     NOT NULL    DEFAULT FALSE,
+    expires        TIMESTAMP   NOT NULL,
+    created        TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of users_identities, links external OpenID Connect subjects to users
+CREATE TABLE users_identities
+(
+    issuer      TEXT        NOT NULL,
+    subject     TEXT        NOT NULL,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+--table of oidc_states, pending single sign-on requests
+CREATE TABLE oidc_states
+(
+    state_hash  TEXT        PRIMARY KEY,
+    verifier    TEXT        NOT NULL,
+    nonce       TEXT        NOT NULL,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of users_email_verifications
+CREATE TABLE users_email_verifications
+(
+    token_hash  TEXT        PRIMARY KEY,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    email       TEXT        NOT NULL,
+    used        BOOLEAN     NOT NULL    DEFAULT FALSE,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of users_impersonations, audit trail of impersonation tokens issued by admins
+CREATE TABLE users_impersonations
+(
+    id          BIGSERIAL   PRIMARY KEY,
+    admin_id    BIGINT      NOT NULL    REFERENCES users,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    session_id  BIGINT      NOT NULL    REFERENCES users_tokens,
+    reason      TEXT        NOT NULL,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+--table of api_keys, scoped keys of service accounts issued by admins
+CREATE TABLE api_keys
+(
+    id          BIGSERIAL   PRIMARY KEY,
+    name        TEXT        NOT NULL,
+    key_prefix  TEXT        NOT NULL,
+    key_hash    TEXT        NOT NULL    UNIQUE,
+    user_id     BIGINT      NOT NULL    REFERENCES users,
+    scopes      TEXT[]      NOT NULL,
+    created_by  BIGINT      NOT NULL    REFERENCES users,
+    revoked     BOOLEAN     NOT NULL    DEFAULT FALSE,
+    last_used   TIMESTAMP,
+    expires     TIMESTAMP   NOT NULL,
+    created     TIMESTAMP   NOT NULL    DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX api_keys_key_prefix_idx ON api_keys (key_prefix);
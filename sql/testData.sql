@@ -1,9 +1,12 @@
 -- admin (password - '12345678')
-INSERT INTO users (username, password, is_admin) VALUES 
-    ('mr. Fedya', '$2a$10$W1uTjnpz.h/hbfWuRhO04ekfs6FffeMsIbtFpxLiFhE6eMgW7oMUi', TRUE)
-ON CONFLICT (username) DO NOTHING
-RETURNING id, username, password, is_admin, active, created;
+INSERT INTO users (username, username_key, password) VALUES 
+    ('mr. Fedya', 'mr. fedya', '$2a$10$W1uTjnpz.h/hbfWuRhO04ekfs6FffeMsIbtFpxLiFhE6eMgW7oMUi')
+ON CONFLICT (username_key) DO NOTHING
+RETURNING id, username, password, active, created;
 
--- default admins token
-INSERT INTO users_tokens (token, user_id) VALUES 
-    ('defaultAdminsToken', 1);
+INSERT INTO users_roles (user_id, role) VALUES 
+    (1, 'admin');
+
+-- default admins token ('defaultAdminsToken'), stored as prefix and SHA-256 digest
+INSERT INTO users_tokens (token_prefix, token_hash, user_id) VALUES 
+    ('defaultA', '24d9490999daf13b7239865c909ef141b1cc181ae3f5903fc981e30e2df09bed', 1);